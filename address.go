@@ -0,0 +1,233 @@
+package vcardenc
+
+import (
+	"strings"
+)
+
+// Address is an ADR value by name rather than by position. The first seven
+// fields are those of RFC 6350; the rest were added by RFC 9554, and an
+// ADR only carries them if one is set. Components holding several values
+// are joined with ", ".
+type Address struct {
+	POBox      string `json:"poBox,omitempty"`
+	Extended   string `json:"extended,omitempty"`
+	Street     string `json:"street,omitempty"`
+	Locality   string `json:"locality,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+
+	Room         string `json:"room,omitempty"`
+	Apartment    string `json:"apartment,omitempty"`
+	Floor        string `json:"floor,omitempty"`
+	StreetNumber string `json:"streetNumber,omitempty"`
+	StreetName   string `json:"streetName,omitempty"`
+	Building     string `json:"building,omitempty"`
+	Block        string `json:"block,omitempty"`
+	Subdistrict  string `json:"subdistrict,omitempty"`
+	District     string `json:"district,omitempty"`
+	Landmark     string `json:"landmark,omitempty"`
+	Direction    string `json:"direction,omitempty"`
+}
+
+// rfc6350ADRComponents is how many ADR components RFC 6350 defines.
+const rfc6350ADRComponents = 7
+
+// fields lists the components of the address in ADR order.
+func (a *Address) fields() []*string {
+	return []*string{
+		&a.POBox, &a.Extended, &a.Street, &a.Locality, &a.Region, &a.PostalCode, &a.Country,
+		&a.Room, &a.Apartment, &a.Floor, &a.StreetNumber, &a.StreetName, &a.Building,
+		&a.Block, &a.Subdistrict, &a.District, &a.Landmark, &a.Direction,
+	}
+}
+
+// Address reads the components of an ADR datum.
+func (datum VcardDatum) Address() (a Address) {
+	for i, field := range a.fields() {
+		*field = strings.Join(datum.Component(i), ", ")
+	}
+	return a
+}
+
+// AddressDatum makes an ADR datum for the address, with seven components
+// unless any of the RFC 9554 ones are set.
+func AddressDatum(a Address, attrs AttrMap) VcardDatum {
+	fields := a.fields()
+	n := rfc6350ADRComponents
+	for i := n; i < len(fields); i++ {
+		if *fields[i] != "" {
+			n = len(fields)
+			break
+		}
+	}
+	values := make([]string, 0, n)
+	for _, field := range fields[:n] {
+		values = append(values, *field)
+	}
+	return SemicolonStructuredDatum("ADR", attrs, values...)
+}
+
+// addressTemplates are postal label layouts by ISO 3166 region. Each line
+// names fields in braces; lines left empty are dropped. {street} is Street,
+// or failing that StreetNumber and StreetName in the local order, and
+// {extended} is Extended, or failing that Building, Floor, Apartment and Room.
+var addressTemplates = map[string]string{
+	"US": "{extended}\n{street}\n{pobox}\n{locality}, {region} {postal}\n{country}",
+	"CA": "{extended}\n{street}\n{pobox}\n{locality} {region} {postal}\n{country}",
+	"AU": "{extended}\n{street}\n{pobox}\n{locality} {region} {postal}\n{country}",
+	"GB": "{extended}\n{street}\n{pobox}\n{locality}\n{region}\n{postal}\n{country}",
+	"IE": "{extended}\n{street}\n{pobox}\n{locality}\n{region}\n{postal}\n{country}",
+	"DE": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"AT": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"CH": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"NL": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"BE": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"DK": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"NO": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"SE": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"FI": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"FR": "{extended}\n{street}\n{pobox}\n{postal} {locality}\n{country}",
+	"ES": "{extended}\n{street}\n{pobox}\n{postal} {locality} {region}\n{country}",
+	"IT": "{extended}\n{street}\n{pobox}\n{postal} {locality} {region}\n{country}",
+	"BR": "{extended}\n{street}\n{pobox}\n{district}\n{locality}-{region}\n{postal}\n{country}",
+	"JP": "〒{postal}\n{region}{locality}{district}\n{street}\n{extended}\n{country}",
+	"CN": "{country}\n{region}{locality}{district}\n{street}\n{extended}\n{postal}",
+}
+
+// defaultAddressTemplate is used for regions without a template of their own.
+const defaultAddressTemplate = "{extended}\n{street}\n{pobox}\n{locality} {region} {postal}\n{country}"
+
+// numberAfterName lists regions that write the street number after the name.
+var numberAfterName = map[string]bool{
+	"DE": true, "AT": true, "CH": true, "NL": true, "BE": true, "DK": true,
+	"NO": true, "SE": true, "FI": true, "ES": true, "IT": true, "BR": true,
+}
+
+// Format lays the address out as a multi-line postal label following the
+// conventions of region, an ISO 3166 code like "US" or "DE".
+func (a Address) Format(region string) string {
+	region = strings.ToUpper(region)
+	template, ok := addressTemplates[region]
+	if !ok {
+		template = defaultAddressTemplate
+	}
+	street := a.Street
+	if street == "" {
+		if numberAfterName[region] {
+			street = joinNonEmpty(" ", a.StreetName, a.StreetNumber)
+		} else {
+			street = joinNonEmpty(" ", a.StreetNumber, a.StreetName)
+		}
+	}
+	extended := a.Extended
+	if extended == "" {
+		extended = joinNonEmpty(", ", a.Building, a.Floor, a.Apartment, a.Room)
+	}
+	var oldnew []string
+	for _, field := range []struct{ name, value string }{
+		{"{pobox}", a.POBox},
+		{"{extended}", extended},
+		{"{street}", street},
+		{"{locality}", a.Locality},
+		{"{region}", a.Region},
+		{"{postal}", a.PostalCode},
+		{"{country}", a.Country},
+		{"{district}", joinNonEmpty(", ", a.Subdistrict, a.District)},
+	} {
+		if field.value == "" {
+			field.value = emptyLabelField
+		}
+		oldnew = append(oldnew, field.name, field.value)
+	}
+	replacer := strings.NewReplacer(oldnew...)
+	var lines []string
+	for _, line := range strings.Split(template, "\n") {
+		if line = cleanLabelLine(replacer.Replace(line)); line != "" && line != "〒" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// emptyLabelField stands in for empty fields until cleanLabelLine drops
+// them along with the separators that came with them.
+const emptyLabelField = "\x00"
+
+func cleanLabelLine(line string) string {
+	for _, sep := range []string{", ", "-"} {
+		line = strings.Replace(line, sep+emptyLabelField, "", -1)
+		line = strings.Replace(line, emptyLabelField+sep, "", -1)
+	}
+	line = strings.Replace(line, emptyLabelField, "", -1)
+	return strings.Join(strings.Fields(line), " ")
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	return strings.Join(dropEmpty(values), sep)
+}
+
+// Label returns the LABEL parameter of an ADR datum.
+func (datum VcardDatum) Label() string {
+	return datum.Attrs.first("LABEL")
+}
+
+// SetLabel sets the LABEL parameter, or removes it if label is "".
+func (datum *VcardDatum) SetLabel(label string) {
+	datum.setOptionalParam("LABEL", label)
+}
+
+// countryRegions maps country names and ISO 3166 alpha-3 codes, in upper
+// case, to the regions of addressTemplates.
+var countryRegions = map[string]string{
+	"USA": "US", "UNITED STATES": "US", "UNITED STATES OF AMERICA": "US",
+	"CAN": "CA", "CANADA": "CA",
+	"AUS": "AU", "AUSTRALIA": "AU",
+	"GBR": "GB", "UK": "GB", "UNITED KINGDOM": "GB", "GREAT BRITAIN": "GB", "ENGLAND": "GB", "SCOTLAND": "GB", "WALES": "GB",
+	"IRL": "IE", "IRELAND": "IE", "ÉIRE": "IE",
+	"DEU": "DE", "GERMANY": "DE", "DEUTSCHLAND": "DE",
+	"AUT": "AT", "AUSTRIA": "AT", "ÖSTERREICH": "AT",
+	"CHE": "CH", "SWITZERLAND": "CH", "SCHWEIZ": "CH", "SUISSE": "CH",
+	"NLD": "NL", "NETHERLANDS": "NL", "THE NETHERLANDS": "NL", "NEDERLAND": "NL",
+	"BEL": "BE", "BELGIUM": "BE", "BELGIQUE": "BE", "BELGIË": "BE",
+	"DNK": "DK", "DENMARK": "DK", "DANMARK": "DK",
+	"NOR": "NO", "NORWAY": "NO", "NORGE": "NO",
+	"SWE": "SE", "SWEDEN": "SE", "SVERIGE": "SE",
+	"FIN": "FI", "FINLAND": "FI", "SUOMI": "FI",
+	"FRA": "FR", "FRANCE": "FR",
+	"ESP": "ES", "SPAIN": "ES", "ESPAÑA": "ES",
+	"ITA": "IT", "ITALY": "IT", "ITALIA": "IT",
+	"BRA": "BR", "BRAZIL": "BR", "BRASIL": "BR",
+	"JPN": "JP", "JAPAN": "JP", "日本": "JP",
+	"CHN": "CN", "CHINA": "CN", "中国": "CN",
+}
+
+// CountryRegion is the ISO 3166 region of the address's Country, which may
+// be an alpha-2 or alpha-3 code or a common name, or "" if it isn't known.
+func (a Address) CountryRegion() string {
+	country := strings.ToUpper(strings.TrimSpace(a.Country))
+	if _, ok := callingCodes[country]; ok {
+		return country
+	}
+	return countryRegions[country]
+}
+
+// FillLabels returns a copy of the card in which every ADR without a LABEL
+// is given one with Address.Format, laid out for the address's own country,
+// or for region if its country isn't known.
+func FillLabels(v Vcard, region string) Vcard {
+	filled := v.withData(make([]VcardDatum, len(v.Data)))
+	for i, d := range v.Data {
+		if strings.EqualFold(d.FieldName, "ADR") && d.Label() == "" {
+			a := d.Address()
+			labelRegion := a.CountryRegion()
+			if labelRegion == "" {
+				labelRegion = region
+			}
+			d.Attrs = copyAttrs(d.Attrs)
+			d.SetLabel(a.Format(labelRegion))
+		}
+		filled.Data[i] = d
+	}
+	return filled
+}
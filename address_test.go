@@ -0,0 +1,80 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type addressTC struct {
+	Address Address
+	Region  string
+}
+
+var (
+	whiteHouse = Address{Street: "1600 Pennsylvania Ave NW", Locality: "Washington", Region: "DC", PostalCode: "20500", Country: "USA"}
+	bundestag  = Address{StreetName: "Platz der Republik", StreetNumber: "1", Locality: "Berlin", PostalCode: "11011", Country: "Germany"}
+	downing    = Address{Street: "10 Downing Street", Locality: "London", PostalCode: "SW1A 2AA"}
+
+	addressFormatTCs = map[addressTC]string{
+		{whiteHouse, "US"}:                       "1600 Pennsylvania Ave NW\nWashington, DC 20500\nUSA",
+		{whiteHouse, "xx"}:                       "1600 Pennsylvania Ave NW\nWashington DC 20500\nUSA",
+		{bundestag, "de"}:                        "Platz der Republik 1\n11011 Berlin\nGermany",
+		{bundestag, "US"}:                        "1 Platz der Republik\nBerlin 11011\nGermany",
+		{downing, "GB"}:                          "10 Downing Street\nLondon\nSW1A 2AA",
+		{Address{Locality: "Springfield"}, "US"}: "Springfield",
+		{Address{PostalCode: "100-0001", Region: "東京都", Locality: "千代田区"}, "JP"}: "〒100-0001\n東京都千代田区",
+	}
+)
+
+func TestAddressFormat(t *testing.T) {
+	for tc, expected := range addressFormatTCs {
+		assert.Equal(t, expected, tc.Address.Format(tc.Region))
+	}
+}
+
+func TestAddressDatum(t *testing.T) {
+	d, err := ParseDatumLine("ADR;TYPE=work:;;1600 Pennsylvania Ave NW;Washington;DC;20500;USA")
+	assert.Nil(t, err)
+	assert.Equal(t, whiteHouse, d.Address())
+	assert.Equal(t, d.StructuredValue, AddressDatum(whiteHouse, nil).StructuredValue)
+
+	out, err := AddressDatum(bundestag, nil).Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "ADR:;;;Berlin;;11011;Germany;;;;1;Platz der Republik;;;;;;\n", out)
+}
+
+func TestFillLabels(t *testing.T) {
+	labelled := AddressDatum(downing, AttrMap{"LABEL": []string{"Number 10"}})
+	leinster := Address{Street: "Kildare Street", Locality: "Dublin 2", Region: "Co. Dublin", PostalCode: "D02 XR20", Country: "Ireland"}
+	card := Vcard{Version: "4.0", Data: []VcardDatum{
+		StringDatum("FN", nil, "Joe Bloggs"),
+		AddressDatum(whiteHouse, AttrMap{"TYPE": []string{"work"}}),
+		labelled,
+		AddressDatum(leinster, nil),
+		AddressDatum(bundestag, nil),
+		AddressDatum(downing, nil),
+	}}
+	filled := FillLabels(card, "GB")
+	assert.Equal(t, "", card.Data[1].Label())
+	assert.Equal(t, whiteHouse.Format("US"), filled.Data[1].Label())
+	assert.Equal(t, "Number 10", filled.Data[2].Label())
+	assert.Equal(t, "Kildare Street\nDublin 2\nCo. Dublin\nD02 XR20\nIreland", filled.Data[3].Label())
+	assert.Equal(t, bundestag.Format("DE"), filled.Data[4].Label())
+	// Without a country, the region given is used.
+	assert.Equal(t, downing.Format("GB"), filled.Data[5].Label())
+
+	out, err := filled.Data[1].Output(nil)
+	assert.Nil(t, err)
+	reparsed, err := ParseDatumLine(out[:len(out)-1])
+	assert.Nil(t, err)
+	assert.Equal(t, whiteHouse.Format("US"), reparsed.Label())
+}
+
+func TestCountryRegion(t *testing.T) {
+	assert.Equal(t, "US", whiteHouse.CountryRegion())
+	assert.Equal(t, "DE", bundestag.CountryRegion())
+	assert.Equal(t, "IE", Address{Country: "ie"}.CountryRegion())
+	assert.Equal(t, "", downing.CountryRegion())
+	assert.Equal(t, "", Address{Country: "Atlantis"}.CountryRegion())
+}
@@ -0,0 +1,230 @@
+package vcardenc
+
+import (
+	"strings"
+)
+
+// Apple's Contacts server predates KIND and MEMBER in 3.0 cards, and still
+// writes groups with these instead.
+const (
+	appleKind   = "X-ADDRESSBOOKSERVER-KIND"
+	appleMember = "X-ADDRESSBOOKSERVER-MEMBER"
+)
+
+// AddressBook is a collection of cards indexed so that MEMBER and RELATED
+// references between them can be resolved. Groups may be written either
+// with KIND and MEMBER or in Apple's X-ADDRESSBOOKSERVER form.
+//
+// The index is built from Cards and PhoneRegion when the AddressBook is
+// made. Callers that change either afterwards, by adding, removing or
+// editing cards, must call Reindex before looking anything up.
+type AddressBook struct {
+	Cards []Vcard
+
+	// PhoneRegion is the region, an ISO 3166 code like "US", that TEL
+	// values and tel: URIs without an international prefix are read as.
+	// If empty, such numbers aren't indexed.
+	PhoneRegion string
+
+	byUID   map[string]int
+	byEmail map[string]int
+	byPhone map[string]int
+}
+
+// DanglingMember is a MEMBER of the group Cards[Group] that doesn't
+// resolve to any card in the AddressBook.
+type DanglingMember struct {
+	Group int
+	URI   string
+}
+
+// NewAddressBook indexes cards by UID, email address and phone number.
+// Where cards share one, the first wins.
+func NewAddressBook(cards []Vcard) *AddressBook {
+	ab := &AddressBook{Cards: cards}
+	ab.Reindex()
+	return ab
+}
+
+// Reindex rebuilds the index from Cards and PhoneRegion, as must be done
+// after changing either.
+func (ab *AddressBook) Reindex() {
+	ab.byUID = make(map[string]int)
+	ab.byEmail = make(map[string]int)
+	ab.byPhone = make(map[string]int)
+	for i, v := range ab.Cards {
+		if uid, ok := v.Get("UID"); ok {
+			addIndex(ab.byUID, uidKey(uid.StringValue), i)
+		}
+		for _, d := range v.GetAll("EMAIL") {
+			if email, err := d.Email(); err == nil {
+				addIndex(ab.byEmail, email.Key(), i)
+			}
+		}
+		for _, d := range v.GetAll("TEL") {
+			if phone, err := d.Phone(ab.PhoneRegion); err == nil {
+				addIndex(ab.byPhone, phone.E164(), i)
+			}
+		}
+	}
+}
+
+func addIndex(index map[string]int, key string, i int) {
+	if _, ok := index[key]; !ok {
+		index[key] = i
+	}
+}
+
+// uidKey is how UIDs are compared: urn:uuid: URIs and bare UUIDs alike by
+// their lower-case UUID, and anything else exactly.
+func uidKey(uid string) string {
+	if len(uid) > 9 && strings.EqualFold(uid[:9], "urn:uuid:") {
+		uid = uid[9:]
+	}
+	if isUUID(uid) {
+		return strings.ToLower(uid)
+	}
+	return uid
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if r != '-' {
+				return false
+			}
+		case !strings.ContainsRune("0123456789abcdefABCDEF", r):
+			return false
+		}
+	}
+	return true
+}
+
+// Lookup finds the card a MEMBER or RELATED URI refers to: by UID for
+// urn:uuid: URIs and anything else, by email address for mailto:, and by
+// number for tel:.
+func (ab *AddressBook) Lookup(uri string) (Vcard, bool) {
+	i, ok := ab.lookupIndex(uri)
+	if !ok {
+		return Vcard{}, false
+	}
+	return ab.Cards[i], true
+}
+
+func (ab *AddressBook) lookupIndex(uri string) (i int, ok bool) {
+	switch lower := strings.ToLower(uri); {
+	case strings.HasPrefix(lower, "mailto:"):
+		if email, err := ParseEmail(uri); err == nil {
+			i, ok = ab.byEmail[email.Key()]
+		}
+	case strings.HasPrefix(lower, "tel:"):
+		if phone, err := ParsePhone(uri, ab.PhoneRegion); err == nil {
+			i, ok = ab.byPhone[phone.E164()]
+		}
+	default:
+		i, ok = ab.byUID[uidKey(uri)]
+	}
+	return i, ok
+}
+
+// IsGroup reports whether the card is a group, by KIND or by Apple's
+// X-ADDRESSBOOKSERVER-KIND.
+func IsGroup(v Vcard) bool {
+	if v.kind() == KindGroup {
+		return true
+	}
+	kind, ok := v.Get(appleKind)
+	return ok && strings.EqualFold(kind.StringValue, "group")
+}
+
+// memberURIs returns the MEMBER, or Apple member, URIs of a group.
+func memberURIs(group Vcard) (uris []string) {
+	for _, d := range group.Data {
+		if strings.EqualFold(d.FieldName, "MEMBER") || strings.EqualFold(d.FieldName, appleMember) {
+			uris = append(uris, d.StringValue)
+		}
+	}
+	return uris
+}
+
+// Groups returns the indices of the groups among Cards.
+func (ab *AddressBook) Groups() (groups []int) {
+	for i, v := range ab.Cards {
+		if IsGroup(v) {
+			groups = append(groups, i)
+		}
+	}
+	return groups
+}
+
+// Members resolves the members of a group, returning the cards found and
+// the URIs that aren't in the AddressBook.
+func (ab *AddressBook) Members(group Vcard) (members []Vcard, dangling []string) {
+	for _, uri := range memberURIs(group) {
+		if member, ok := ab.Lookup(uri); ok {
+			members = append(members, member)
+		} else {
+			dangling = append(dangling, uri)
+		}
+	}
+	return members, dangling
+}
+
+// Dangling returns every unresolvable MEMBER of every group in the
+// AddressBook, in order.
+func (ab *AddressBook) Dangling() (dangling []DanglingMember) {
+	for _, i := range ab.Groups() {
+		_, uris := ab.Members(ab.Cards[i])
+		for _, uri := range uris {
+			dangling = append(dangling, DanglingMember{i, uri})
+		}
+	}
+	return dangling
+}
+
+// ToAppleGroup rewrites a KIND:group card in Apple's form, as a 3.0 card
+// with X-ADDRESSBOOKSERVER-KIND and X-ADDRESSBOOKSERVER-MEMBER. Apple only
+// understands urn:uuid: members, so others are left as MEMBER.
+func ToAppleGroup(v Vcard) Vcard {
+	return convertGroup(v, "3.0", func(d VcardDatum) VcardDatum {
+		switch {
+		case strings.EqualFold(d.FieldName, "KIND"):
+			return StringDatum(appleKind, nil, strings.ToLower(d.StringValue))
+		case strings.EqualFold(d.FieldName, "MEMBER") && strings.HasPrefix(strings.ToLower(d.StringValue), "urn:uuid:"):
+			return StringDatum(appleMember, nil, d.StringValue)
+		}
+		return d
+	})
+}
+
+// FromAppleGroup is the inverse of ToAppleGroup, giving a 4.0 card with
+// KIND and MEMBER.
+func FromAppleGroup(v Vcard) Vcard {
+	return convertGroup(v, "4.0", func(d VcardDatum) VcardDatum {
+		switch {
+		case strings.EqualFold(d.FieldName, appleKind):
+			return StringDatum("KIND", nil, strings.ToLower(d.StringValue))
+		case strings.EqualFold(d.FieldName, appleMember):
+			return StringDatum("MEMBER", nil, d.StringValue)
+		}
+		return d
+	})
+}
+
+// convertGroup copies a group card to the given version, converting each
+// datum. Cards that aren't groups are returned as they are.
+func convertGroup(v Vcard, version string, convert func(VcardDatum) VcardDatum) Vcard {
+	if !IsGroup(v) {
+		return v
+	}
+	v = v.SyncData()
+	converted := Vcard{Version: version, Data: make([]VcardDatum, len(v.Data))}
+	for i, d := range v.Data {
+		converted.Data[i] = convert(d)
+	}
+	return converted.SyncFields()
+}
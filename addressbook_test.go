@@ -0,0 +1,103 @@
+package vcardenc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var addressBookCards = `BEGIN:VCARD
+VERSION:4.0
+UID:urn:uuid:03A0E51F-D1AA-4385-8A08-D91E8E2C9B1A
+FN:Jane Doe
+END:VCARD
+BEGIN:VCARD
+VERSION:4.0
+FN:John Doe
+EMAIL:john.doe@Example.com
+TEL;VALUE=uri:tel:+1-555-555-5555
+END:VCARD
+BEGIN:VCARD
+VERSION:4.0
+KIND:group
+FN:The Doe family
+MEMBER:urn:uuid:03a0e51f-d1aa-4385-8a08-d91e8e2c9b1a
+MEMBER:mailto:john.doe@example.com
+MEMBER:urn:uuid:b8767877-b4a1-4c70-9acc-505d3819e519
+END:VCARD
+BEGIN:VCARD
+VERSION:3.0
+X-ADDRESSBOOKSERVER-KIND:group
+FN:Phone friends
+X-ADDRESSBOOKSERVER-MEMBER:tel:+15555555555
+END:VCARD`
+
+func TestAddressBook(t *testing.T) {
+	cards, err := ParseVcards(addressBookCards, nil)
+	assert.Nil(t, err)
+	ab := NewAddressBook(cards)
+	assert.Equal(t, []int{2, 3}, ab.Groups())
+
+	members, dangling := ab.Members(cards[2])
+	assert.Equal(t, []Vcard{cards[0], cards[1]}, members)
+	assert.Equal(t, []string{"urn:uuid:b8767877-b4a1-4c70-9acc-505d3819e519"}, dangling)
+
+	members, dangling = ab.Members(cards[3])
+	assert.Equal(t, []Vcard{cards[1]}, members)
+	assert.Empty(t, dangling)
+
+	assert.Equal(t, []DanglingMember{{2, "urn:uuid:b8767877-b4a1-4c70-9acc-505d3819e519"}}, ab.Dangling())
+
+	_, ok := ab.Lookup("03A0E51F-D1AA-4385-8A08-D91E8E2C9B1A")
+	assert.True(t, ok)
+	_, ok = ab.Lookup("mailto:nobody@example.com")
+	assert.False(t, ok)
+}
+
+func TestAddressBookReindex(t *testing.T) {
+	cards, err := ParseVcards(addressBookCards, nil)
+	assert.Nil(t, err)
+	ab := NewAddressBook(cards)
+	local := Vcard{Version: "4.0", Data: []VcardDatum{
+		StringDatum("FN", nil, "Bubba Blue"),
+		StringDatum("UID", nil, "bubba"),
+		StringDatum("TEL", nil, "(404) 555-1212"),
+	}}
+	ab.Cards = append(ab.Cards, local)
+	_, ok := ab.Lookup("bubba")
+	assert.False(t, ok, "not until reindexed")
+
+	ab.Reindex()
+	found, ok := ab.Lookup("bubba")
+	assert.True(t, ok)
+	assert.Equal(t, local, found)
+	_, ok = ab.Lookup("tel:+14045551212")
+	assert.False(t, ok, "national numbers need a region")
+
+	ab.PhoneRegion = "US"
+	ab.Reindex()
+	found, ok = ab.Lookup("tel:+1-404-555-1212")
+	assert.True(t, ok)
+	assert.Equal(t, local, found)
+	_, ok = ab.Lookup("tel:404-555-1212")
+	assert.True(t, ok)
+}
+
+func TestAppleGroups(t *testing.T) {
+	cards, err := ParseVcards(addressBookCards, nil)
+	assert.Nil(t, err)
+
+	apple := ToAppleGroup(cards[2])
+	assert.Equal(t, "3.0", apple.Version)
+	encoded, err := apple.Encode(nil)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "BEGIN:VCARD\nVERSION:3.0\n"), encoded)
+	assert.Len(t, apple.GetAll("X-ADDRESSBOOKSERVER-MEMBER"), 2)
+	assert.Len(t, apple.GetAll("MEMBER"), 1, "mailto: members have no Apple form")
+	kind, _ := apple.Get("X-ADDRESSBOOKSERVER-KIND")
+	assert.Equal(t, "group", kind.StringValue)
+	assert.Equal(t, cards[2], FromAppleGroup(apple))
+
+	assert.Equal(t, cards[0], ToAppleGroup(cards[0]), "individuals are left alone")
+}
@@ -0,0 +1,87 @@
+package vcardenc
+
+import (
+	"sort"
+	"strings"
+)
+
+// Alternatives returns the data named fieldName grouped by ALTID, so each
+// group holds alternative representations of the same value, such as one
+// name in several languages. Data without an ALTID are groups of their own.
+// Groups are in the order their first member appears in the card.
+func (v Vcard) Alternatives(fieldName string) (groups [][]VcardDatum) {
+	indices := make(map[string]int)
+	for _, d := range v.GetAll(fieldName) {
+		altID := d.AltID()
+		if altID == "" {
+			groups = append(groups, []VcardDatum{d})
+			continue
+		}
+		if i, ok := indices[altID]; ok {
+			groups[i] = append(groups[i], d)
+			continue
+		}
+		indices[altID] = len(groups)
+		groups = append(groups, []VcardDatum{d})
+	}
+	return groups
+}
+
+// Localized returns one datum named fieldName per ALTID group, picking from
+// each the representation whose LANGUAGE best matches languages, a list of
+// BCP 47 language ranges in order of preference, like "de-CH" or "en".
+// Groups with no match give their representation without a LANGUAGE, or
+// failing that their most preferred one.
+func (v Vcard) Localized(fieldName string, languages ...string) (localized []VcardDatum) {
+	for _, group := range v.Alternatives(fieldName) {
+		localized = append(localized, chooseLocalized(group, languages))
+	}
+	return localized
+}
+
+func chooseLocalized(group []VcardDatum, languages []string) VcardDatum {
+	for _, lr := range languages {
+		for r := lr; r != ""; r = truncateLanguageRange(r) {
+			for _, d := range group {
+				if languageMatches(r, d.Language()) {
+					return d
+				}
+			}
+		}
+	}
+	for _, d := range group {
+		if d.Language() == "" {
+			return d
+		}
+	}
+	ranked := append([]VcardDatum(nil), group...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].preference() < ranked[j].preference()
+	})
+	return ranked[0]
+}
+
+// languageMatches reports whether tag falls within the language range r as
+// in RFC 4647 basic filtering: "de" matches "de" and "de-CH", but not "den".
+func languageMatches(r, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	r, tag = strings.ToLower(r), strings.ToLower(tag)
+	return r == "*" || tag == r || strings.HasPrefix(tag, r+"-")
+}
+
+// truncateLanguageRange drops the last subtag of a language range, and any
+// single-character subtag left dangling, as in RFC 4647 lookup.
+// "zh-Hant-CN-x-private1" becomes "zh-Hant-CN", then "zh-Hant", then "zh".
+func truncateLanguageRange(r string) string {
+	dash := strings.LastIndex(r, "-")
+	if dash == -1 {
+		return ""
+	}
+	r = r[:dash]
+	if dash = strings.LastIndex(r, "-"); dash != -1 && len(r)-dash == 2 {
+		r = r[:dash]
+	}
+	return r
+}
@@ -0,0 +1,60 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	multilingualCard = Vcard{
+		Data: []VcardDatum{
+			StringDatum("FN", nil, "Forrest Gump"),
+			SemicolonStructuredDatum("N", AttrMap{"ALTID": []string{"1"}, "LANGUAGE": []string{"en"}}, "Gump", "Forrest", "", "", ""),
+			SemicolonStructuredDatum("N", AttrMap{"ALTID": []string{"1"}, "LANGUAGE": []string{"ja-Hani"}}, "ガンプ", "フォレスト", "", "", ""),
+			StringDatum("TITLE", AttrMap{"ALTID": []string{"1"}, "LANGUAGE": []string{"en-US"}}, "Shrimp Man"),
+			StringDatum("TITLE", AttrMap{"ALTID": []string{"1"}, "LANGUAGE": []string{"fr"}}, "Homme crevette"),
+			StringDatum("TITLE", nil, "Captain"),
+		},
+	}
+
+	languageTruncationTCs = map[string]string{
+		"zh-Hant-CN-x-private1": "zh-Hant-CN",
+		"zh-Hant-CN":            "zh-Hant",
+		"de":                    "",
+	}
+)
+
+func TestAlternatives(t *testing.T) {
+	groups := multilingualCard.Alternatives("TITLE")
+	assert.Len(t, groups, 2)
+	assert.Len(t, groups[0], 2)
+	assert.Len(t, groups[1], 1)
+}
+
+func TestLocalized(t *testing.T) {
+	titles := multilingualCard.Localized("TITLE", "fr-CA", "en")
+	assert.Len(t, titles, 2)
+	assert.Equal(t, "Homme crevette", titles[0].StringValue)
+	assert.Equal(t, "Captain", titles[1].StringValue)
+
+	titles = multilingualCard.Localized("TITLE", "en")
+	assert.Equal(t, "Shrimp Man", titles[0].StringValue)
+
+	names := multilingualCard.Localized("N", "ja")
+	assert.Equal(t, []string{"ガンプ"}, names[0].Component(0))
+	names = multilingualCard.Localized("N", "de")
+	assert.Equal(t, []string{"Gump"}, names[0].Component(0))
+}
+
+func TestTruncateLanguageRange(t *testing.T) {
+	for r, expected := range languageTruncationTCs {
+		assert.Equal(t, expected, truncateLanguageRange(r))
+	}
+}
+
+func TestValidateAltIDCardinality(t *testing.T) {
+	assert.Empty(t, DefaultRegistry.Validate(multilingualCard))
+	twoNames := Vcard{Data: append(multilingualCard.Data, SemicolonStructuredDatum("N", nil, "Gump", "", "", "", ""))}
+	assert.Len(t, DefaultRegistry.Validate(twoNames), 1)
+}
@@ -38,8 +38,33 @@ func isValidType(t valueType) bool {
 
 // AttrMap is a map of attributes. When represented in card form, this is sorted,
 // but this is only to assist in testing and isn't strictly part of the spec.
+// Parsed keys are upper case; use Get to look up keys of unknown case.
 type AttrMap map[string][]string
 
+// Get returns the values of the parameter named key, ignoring case.
+func (attrs AttrMap) Get(key string) []string {
+	if values, ok := attrs[key]; ok {
+		return values
+	}
+	for k, values := range attrs {
+		if strings.EqualFold(k, key) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Spelling records how a parsed datum's field and parameter names were
+// written, where that differs from their canonical upper case, so that
+// they can be re-emitted as found with EncodeOptions.PreserveCase.
+type Spelling struct {
+	// FieldName is the field name as written, if not upper case.
+	FieldName string
+	// Attrs maps canonical parameter names to the spelling of each of their
+	// values as written, as "type=HOME;Type=voice" spells TYPE twice.
+	Attrs map[string][]string
+}
+
 // VcardDatum is a single or multiple line key:value entry in a vcard.
 type VcardDatum struct {
 	// FieldName is the name of the field. For "Begin:vcard"
@@ -53,9 +78,12 @@ type VcardDatum struct {
 	// ValueType is either "structured" or "string".
 	ValueType valueType `json:"valueType"`
 
-	// StructuredValue is the value if ValueType is "structured",
-	// and is represented in vcard as a semicolon-delimited list of strings.
-	StructuredValue []string `json:"structuredValue,omitempty"`
+	// StructuredValue is the value if ValueType is "structured". Each entry
+	// is a component, and each component is a list of values; in vcard the
+	// components are delimited by semicolons and the values by commas, so
+	// "N:Public;John;Quinlan,Adams;;" has a third component of two values.
+	// A comma-structured value has a single component.
+	StructuredValue [][]string `json:"structuredValue,omitempty"`
 
 	// StringValue is the value if ValueType is "string",
 	// and is represented in vcard as a free (possibly wrapped)string
@@ -64,6 +92,10 @@ type VcardDatum struct {
 
 	// BinaryValue is the value if ValueType is "binary"
 	BinaryValue []byte `json:"binaryValue,omitempty"`
+
+	// Spelling is set on parsed data whose names weren't written in upper
+	// case, and is nil otherwise.
+	Spelling *Spelling `json:"-"`
 }
 
 // StringDatum is a shortcut for making a string-type datum.
@@ -86,20 +118,94 @@ func CommaStructuredDatum(fieldName string, attrs AttrMap, fieldValues ...string
 	return VcardDatum{
 		FieldName:       fieldName,
 		ValueType:       CommaStructuredValueType,
-		StructuredValue: fieldValues,
+		StructuredValue: [][]string{fieldValues},
 		Attrs:           attrs,
 	}
 }
 
 // SemicolonStructuredDatum makes construcing a SemicolonStructuredValueType easy.
+// Each fieldValue is a single-valued component; use StructuredDatum if any
+// component needs several values.
 func SemicolonStructuredDatum(fieldName string, attrs AttrMap, fieldValues ...string) VcardDatum {
+	components := make([][]string, 0, len(fieldValues))
+	for _, v := range fieldValues {
+		components = append(components, []string{v})
+	}
+	return StructuredDatum(fieldName, attrs, components...)
+}
+
+// StructuredDatum makes a SemicolonStructuredValueType from components that
+// may each hold several values, like the additional names in N.
+func StructuredDatum(fieldName string, attrs AttrMap, components ...[]string) VcardDatum {
 	return VcardDatum{
 		FieldName:       fieldName,
 		ValueType:       SemicolonStructuredValueType,
-		StructuredValue: fieldValues,
+		StructuredValue: components,
 		Attrs:           attrs,
 	}
+}
+
+// Component returns the values of the i'th structured component, or nil
+// if there aren't that many components.
+func (datum VcardDatum) Component(i int) []string {
+	if i < 0 || i >= len(datum.StructuredValue) {
+		return nil
+	}
+	return datum.StructuredValue[i]
+}
 
+// Equal reports whether two data encode identically, so that the order and
+// casing of parameter names, and the case of the field name, don't matter.
+func (datum VcardDatum) Equal(other VcardDatum) bool {
+	return datum.equalWith(other, EncodeOptions{})
+}
+
+// equalWith is Equal, encoding as opts say.
+func (datum VcardDatum) equalWith(other VcardDatum, opts EncodeOptions) bool {
+	dout, derr := datum.OutputWith(opts)
+	oout, oerr := other.OutputWith(opts)
+	return derr == nil && oerr == nil && dout == oout
+}
+
+// outputName is the field name to write: upper case, unless preserveCase
+// asks for the name as parsed or given.
+func (datum VcardDatum) outputName(preserveCase bool) string {
+	if !preserveCase {
+		return strings.ToUpper(datum.FieldName)
+	}
+	if datum.Spelling != nil && datum.Spelling.FieldName != "" {
+		return datum.Spelling.FieldName
+	}
+	return datum.FieldName
+}
+
+// outputParams writes the values of a parameter: under its upper-case name,
+// unless preserveCase asks for names as parsed, where values spelt
+// differently are written as separate parameters.
+func (datum VcardDatum) outputParams(key string, values []string, preserveCase bool) []orderableKV {
+	if !preserveCase {
+		return []orderableKV{{escape(strings.ToUpper(key)), paramJoin(values)}}
+	}
+	var spelt []string
+	if datum.Spelling != nil {
+		spelt = datum.Spelling.Attrs[key]
+	}
+	switch {
+	case len(spelt) == 0:
+		return []orderableKV{{escape(key), paramJoin(values)}}
+	case len(spelt) != len(values):
+		// Values were changed since parsing, so which is which is lost.
+		return []orderableKV{{escape(spelt[0]), paramJoin(values)}}
+	}
+	var kvs []orderableKV
+	start := 0
+	for i := 1; i <= len(values); i++ {
+		if i == len(values) || spelt[i] != spelt[start] {
+			kvs = append(kvs, orderableKV{escape(spelt[start]), paramJoin(values[start:i])})
+			start = i
+		}
+	}
+	return kvs
 }
 
 type orderableKV struct {
@@ -119,7 +225,7 @@ func (okvs orderableKVs) Len() int {
 // Less reports whether the element with
 // index i should sort before the element with index j.
 func (okvs orderableKVs) Less(i, j int) bool {
-	return okvs[i].Key > okvs[j].Key
+	return strings.ToUpper(okvs[i].Key) > strings.ToUpper(okvs[j].Key)
 }
 
 // Swap swaps the elements with indexes i and j.
@@ -134,37 +240,48 @@ func (okvs orderableKVs) Swap(i, j int) {
 // that override this default behaviour, because vCard is the shittiest
 // encoding format ever.
 func (datum VcardDatum) Output(specialRules map[string]DatumEncoder) (string, error) {
-	if specialFunc, ok := specialRules[datum.FieldName]; ok {
+	return datum.OutputWith(EncodeOptions{SpecialRules: specialRules})
+}
+
+// OutputWith is Output, configured by opts.
+func (datum VcardDatum) OutputWith(opts EncodeOptions) (string, error) {
+	if specialFunc, ok := lookupRule(opts.SpecialRules, datum.FieldName); ok {
 		return specialFunc(datum)
 	}
+	if spec, ok := opts.registry().Lookup(datum.FieldName); ok && spec.Encoder != nil {
+		return spec.Encoder(datum)
+	}
 	if !isValidType(datum.ValueType) {
 		return "", ErrBadDatumType
 	}
 	var buf string
-	buf += strings.ToUpper(datum.FieldName)
+	buf += datum.outputName(opts.PreserveCase)
 	kvs := make(orderableKVs, 0, len(datum.Attrs))
 	for key, values := range datum.Attrs {
-		safekey := escape(key)
-		safeval := escapedJoin(values, ",")
-		kvs = append(kvs, orderableKV{safekey, safeval})
+		kvs = append(kvs, datum.outputParams(key, values, opts.PreserveCase)...)
 	}
-	sort.Sort(kvs)
+	// Stable, so that a parameter spelt several ways keeps its order.
+	sort.Stable(kvs)
 	for _, kv := range kvs {
 		buf += ";" + kv.Key + "=" + kv.Value
 	}
 	switch datum.ValueType {
 	case StringValueType:
 		{
-			// Escape and rune-wrap the output data.
-			buf += ":" + escape(datum.StringValue)
+			// Escape and rune-wrap the output data, unless it's a URI.
+			if opts.registry().isURIValued(datum.FieldName, datum.Attrs) {
+				buf += ":" + datum.StringValue
+			} else {
+				buf += ":" + escape(datum.StringValue)
+			}
 		}
 	case SemicolonStructuredValueType:
 		{
-			buf += ":" + escapedJoin(datum.StructuredValue, ";")
+			buf += ":" + structuredJoin(datum.StructuredValue)
 		}
 	case CommaStructuredValueType:
 		{
-			buf += ":" + escapedJoin(datum.StructuredValue, ",")
+			buf += ":" + componentJoin(flattenComponents(datum.StructuredValue))
 		}
 	case BinaryValueType:
 		{
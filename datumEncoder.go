@@ -1,7 +1,71 @@
 package vcardenc
 
+import (
+	"strings"
+	"time"
+)
+
 // DatumEncoder is a function that can handle a Datum.
 // It is used to establish a map of special rules for particular
 // fieldnames which should be used when encoding a fieldname, because
 // vCard is absolute shit and requires crazy special-case rules.
 type DatumEncoder func(VcardDatum) (string, error)
+
+// DatumDecoder is the parsing counterpart of DatumEncoder. It is given
+// the field name, attributes and raw (still escaped) value of an unwrapped
+// line and returns the datum it represents.
+type DatumDecoder func(fieldName string, attrs AttrMap, rawValue string) (VcardDatum, error)
+
+// EncodeOptions configure Vcard.EncodeWith and VcardDatum.OutputWith. The
+// ProdID and REV options only apply to whole cards.
+type EncodeOptions struct {
+	// SpecialRules maps FieldNames to encoders that override the default
+	// encoding. FieldNames are matched ignoring case.
+	SpecialRules map[string]DatumEncoder
+
+	// PreserveCase writes field and parameter names as they were parsed
+	// or given, instead of in upper case, for byte-for-byte fidelity.
+	PreserveCase bool
+
+	// Registry supplies the Encoders of properties without a special rule
+	// and decides which have URI values, which are written without
+	// escaping. If nil, DefaultRegistry is used.
+	Registry *Registry
+
+	// ProdID, if set, is written as the PRODID of cards, replacing any
+	// they have, like "-//Example Corp//vcardenc//EN".
+	ProdID string
+
+	// StampRev sets REV to the current time, unless PreviousETag says the
+	// card hasn't changed, in which case any REV it has is kept.
+	StampRev bool
+
+	// PreviousETag is the ETag of the card as last stored, if known.
+	PreviousETag string
+
+	// Clock returns the current time for StampRev. If nil, time.Now is used.
+	Clock func() time.Time
+}
+
+// registry is the Registry to encode with.
+func (opts EncodeOptions) registry() *Registry {
+	if opts.Registry == nil {
+		return DefaultRegistry
+	}
+	return opts.Registry
+}
+
+// lookupRule finds the DatumEncoder or DatumDecoder for fieldName,
+// preferring an exact match over one that differs in case.
+func lookupRule[R DatumEncoder | DatumDecoder](rules map[string]R, fieldName string) (R, bool) {
+	if rule, ok := rules[fieldName]; ok {
+		return rule, true
+	}
+	for name, rule := range rules {
+		if strings.EqualFold(name, fieldName) {
+			return rule, true
+		}
+	}
+	var none R
+	return none, false
+}
@@ -0,0 +1,296 @@
+/*
+Package dedupe finds and merges likely duplicate contacts in an address
+book, which rescued exports are invariably full of.
+*/
+package dedupe
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cathalgarvey/vcardenc"
+)
+
+// Reason is why two cards were judged duplicates.
+type Reason string
+
+const (
+	// SameUID cards share a UID.
+	SameUID Reason = "uid"
+	// SameEmail cards share a normalized email address.
+	SameEmail Reason = "email"
+	// SamePhone cards share a phone number, compared in E.164 form.
+	SamePhone Reason = "phone"
+	// SimilarName cards have names at least Options.NameThreshold similar.
+	SimilarName Reason = "name"
+)
+
+// Options tune how eagerly cards are judged duplicates.
+type Options struct {
+	// NameThreshold is the similarity, from 0 to 1, that names must reach
+	// for the names alone to make cards duplicates. Zero disables name matching.
+	NameThreshold float64
+
+	// PhoneRegion is the region, an ISO 3166 code like "US", that numbers
+	// written without an international prefix are read as, so that
+	// "+1 404 555 1212" and "(404) 555-1212" match, both in Find and when
+	// merging. If empty, such numbers aren't compared.
+	PhoneRegion string
+
+	// IgnoreUID, IgnoreEmail and IgnorePhone disable matching on those
+	// properties.
+	IgnoreUID, IgnoreEmail, IgnorePhone bool
+}
+
+// DefaultOptions are reasonably conservative.
+var DefaultOptions = Options{NameThreshold: 0.9}
+
+// Suggestion is a pair of cards, by index, judged to be duplicates.
+type Suggestion struct {
+	A, B    int
+	Reasons []Reason
+	// NameSimilarity is the similarity of the cards' names, from 0 to 1.
+	NameSimilarity float64
+}
+
+// Cluster is a group of cards judged to be the same contact.
+type Cluster struct {
+	// Indices of the cards in the slice given to Find, in order.
+	Indices []int
+	// Suggestions are the pairwise matches that joined the cluster.
+	Suggestions []Suggestion
+	// Merged is the cards of the cluster merged with MergeCards.
+	Merged vcardenc.Vcard
+}
+
+// Find clusters likely duplicates among cards. Only clusters of two or more
+// cards are returned, in order of their first card.
+func Find(cards []vcardenc.Vcard, opts Options) (clusters []Cluster) {
+	keys := make([]cardKeys, len(cards))
+	for i, card := range cards {
+		keys[i] = makeCardKeys(card, opts)
+	}
+	var suggestions []Suggestion
+	for i := range cards {
+		for j := i + 1; j < len(cards); j++ {
+			if s, ok := compare(keys[i], keys[j], opts); ok {
+				s.A, s.B = i, j
+				suggestions = append(suggestions, s)
+			}
+		}
+	}
+	parents := make([]int, len(cards))
+	for i := range parents {
+		parents[i] = i
+	}
+	for _, s := range suggestions {
+		union(parents, s.A, s.B)
+	}
+	byRoot := make(map[int]*Cluster)
+	var roots []int
+	for i := range cards {
+		root := find(parents, i)
+		c, ok := byRoot[root]
+		if !ok {
+			c = &Cluster{}
+			byRoot[root] = c
+			roots = append(roots, root)
+		}
+		c.Indices = append(c.Indices, i)
+	}
+	for _, s := range suggestions {
+		c := byRoot[find(parents, s.A)]
+		c.Suggestions = append(c.Suggestions, s)
+	}
+	for _, root := range roots {
+		c := byRoot[root]
+		if len(c.Indices) < 2 {
+			continue
+		}
+		members := make([]vcardenc.Vcard, 0, len(c.Indices))
+		for _, i := range c.Indices {
+			members = append(members, cards[i])
+		}
+		c.Merged = MergeCardsWith(opts, members...)
+		clusters = append(clusters, *c)
+	}
+	return clusters
+}
+
+// MergeCards unions the data of cards into one card. Properties that may only
+// appear once, and FN, are taken from the first card that has them; the rest
+// are kept from every card, skipping any datum equal to one already kept, and
+// any EMAIL or TEL whose address or number is already kept in another form.
+func MergeCards(cards ...vcardenc.Vcard) vcardenc.Vcard {
+	return MergeCardsWith(DefaultOptions, cards...)
+}
+
+// MergeCardsWith is MergeCards, reading phone numbers in opts.PhoneRegion.
+func MergeCardsWith(opts Options, cards ...vcardenc.Vcard) (merged vcardenc.Vcard) {
+	single := make(map[string]bool)
+	for _, card := range cards {
+		if merged.Version == "" {
+			merged.Version = card.Version
+		}
+		for _, d := range card.SyncData().Data {
+			name := strings.ToUpper(d.FieldName)
+			if isSingular(name) {
+				if single[name] {
+					continue
+				}
+				single[name] = true
+			}
+			if !containsDatum(merged.Data, d, opts.PhoneRegion) {
+				merged.Data = append(merged.Data, d)
+			}
+		}
+	}
+	return merged.SyncFields()
+}
+
+func isSingular(name string) bool {
+	if name == "FN" {
+		return true
+	}
+	spec, ok := vcardenc.DefaultRegistry.Lookup(name)
+	return ok && (spec.Cardinality == vcardenc.CardinalityAtMostOne || spec.Cardinality == vcardenc.CardinalityExactlyOne)
+}
+
+func containsDatum(data []vcardenc.VcardDatum, d vcardenc.VcardDatum, region string) bool {
+	key := datumKey(d, region)
+	for _, e := range data {
+		if e.Equal(d) {
+			return true
+		}
+		if key != "" && strings.EqualFold(e.FieldName, d.FieldName) && datumKey(e, region) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// datumKey is the normalized value of an EMAIL or TEL, or "" for other
+// properties and values that don't parse.
+func datumKey(d vcardenc.VcardDatum, region string) string {
+	switch strings.ToUpper(d.FieldName) {
+	case "EMAIL":
+		return normalizeEmail(d.StringValue)
+	case "TEL":
+		return normalizePhone(d.StringValue, region)
+	}
+	return ""
+}
+
+// cardKeys are the normalized values of a card that Find compares.
+type cardKeys struct {
+	uid    string
+	emails []string
+	phones []string
+	name   string
+}
+
+func makeCardKeys(card vcardenc.Vcard, opts Options) (k cardKeys) {
+	if d, ok := card.Get("UID"); ok && !opts.IgnoreUID {
+		k.uid = strings.TrimSpace(d.StringValue)
+	}
+	if !opts.IgnoreEmail {
+		for _, d := range card.GetAll("EMAIL") {
+			if email := normalizeEmail(d.StringValue); email != "" {
+				k.emails = append(k.emails, email)
+			}
+		}
+	}
+	if !opts.IgnorePhone {
+		for _, d := range card.GetAll("TEL") {
+			if phone := normalizePhone(d.StringValue, opts.PhoneRegion); phone != "" {
+				k.phones = append(k.phones, phone)
+			}
+		}
+	}
+	k.name = cardName(card)
+	return k
+}
+
+func compare(x, y cardKeys, opts Options) (s Suggestion, ok bool) {
+	if x.uid != "" && x.uid == y.uid {
+		s.Reasons = append(s.Reasons, SameUID)
+	}
+	if sharesAny(x.emails, y.emails) {
+		s.Reasons = append(s.Reasons, SameEmail)
+	}
+	if sharesAny(x.phones, y.phones) {
+		s.Reasons = append(s.Reasons, SamePhone)
+	}
+	if x.name != "" && y.name != "" {
+		s.NameSimilarity = vcardenc.Similarity(x.name, y.name)
+		if opts.NameThreshold > 0 && s.NameSimilarity >= opts.NameThreshold {
+			s.Reasons = append(s.Reasons, SimilarName)
+		}
+	}
+	return s, len(s.Reasons) > 0
+}
+
+func sharesAny(xs, ys []string) bool {
+	for _, x := range xs {
+		for _, y := range ys {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeEmail is the comparison key of an address, or "" if it isn't one.
+func normalizeEmail(email string) string {
+	parsed, err := vcardenc.ParseEmail(email)
+	if err != nil {
+		return ""
+	}
+	return parsed.Key()
+}
+
+// normalizePhone is the E.164 form of a number, or "" if it isn't one.
+func normalizePhone(phone, region string) string {
+	parsed, err := vcardenc.ParsePhone(phone, region)
+	if err != nil {
+		return ""
+	}
+	return parsed.E164()
+}
+
+// cardName is FN, or failing that N, lower-cased with punctuation dropped
+// and words sorted, so that "Gump, Forrest" and "Forrest Gump" compare equal.
+func cardName(card vcardenc.Vcard) string {
+	var raw string
+	if d, ok := card.Get("FN"); ok {
+		raw = d.StringValue
+	} else if d, ok := card.Get("N"); ok {
+		for _, c := range d.StructuredValue {
+			raw += " " + strings.Join(c, " ")
+		}
+	}
+	words := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+func find(parents []int, i int) int {
+	for parents[i] != i {
+		parents[i] = parents[parents[i]]
+		i = parents[i]
+	}
+	return i
+}
+
+func union(parents []int, i, j int) {
+	ri, rj := find(parents, i), find(parents, j)
+	if ri < rj {
+		parents[rj] = ri
+	} else if rj < ri {
+		parents[ri] = rj
+	}
+}
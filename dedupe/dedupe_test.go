@@ -0,0 +1,77 @@
+package dedupe
+
+import (
+	"testing"
+
+	"github.com/cathalgarvey/vcardenc"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	addressBook = []vcardenc.Vcard{
+		{Data: []vcardenc.VcardDatum{
+			vcardenc.StringDatum("FN", nil, "Forrest Gump"),
+			vcardenc.StringDatum("EMAIL", nil, "forrestgump@example.com"),
+			vcardenc.StringDatum("TEL", vcardenc.AttrMap{"TYPE": []string{"home"}}, "tel:+14045551212"),
+		}},
+		{Data: []vcardenc.VcardDatum{
+			vcardenc.StringDatum("FN", nil, "Jenny Curran"),
+			vcardenc.StringDatum("EMAIL", nil, "jenny@example.com"),
+		}},
+		{Data: []vcardenc.VcardDatum{
+			vcardenc.StringDatum("FN", nil, "Gump, Forrest"),
+			vcardenc.StringDatum("TEL", vcardenc.AttrMap{"TYPE": []string{"home"}}, "(404) 555-1212"),
+			vcardenc.StringDatum("NOTE", nil, "Likes shrimp"),
+		}},
+		{Data: []vcardenc.VcardDatum{
+			vcardenc.StringDatum("FN", nil, "F. Gump"),
+			vcardenc.StringDatum("EMAIL", nil, "mailto:ForrestGump@Example.com"),
+			vcardenc.StringDatum("NOTE", nil, "Likes shrimp"),
+		}},
+	}
+)
+
+func TestFind(t *testing.T) {
+	opts := DefaultOptions
+	opts.PhoneRegion = "US"
+	clusters := Find(addressBook, opts)
+	assert.Len(t, clusters, 1)
+	c := clusters[0]
+	assert.Equal(t, []int{0, 2, 3}, c.Indices)
+	assert.Len(t, c.Suggestions, 2)
+	assert.Equal(t, []Reason{SamePhone, SimilarName}, c.Suggestions[0].Reasons)
+	assert.Equal(t, []Reason{SameEmail}, c.Suggestions[1].Reasons)
+	assert.EqualValues(t, []vcardenc.VcardDatum{
+		vcardenc.StringDatum("FN", nil, "Forrest Gump"),
+		vcardenc.StringDatum("EMAIL", nil, "forrestgump@example.com"),
+		vcardenc.StringDatum("TEL", vcardenc.AttrMap{"TYPE": []string{"home"}}, "tel:+14045551212"),
+		vcardenc.StringDatum("NOTE", nil, "Likes shrimp"),
+	}, c.Merged.Data)
+}
+
+func TestMergeCards(t *testing.T) {
+	a := vcardenc.Vcard{Data: []vcardenc.VcardDatum{
+		vcardenc.StringDatum("TEL", nil, "tel:+14045551212"),
+		vcardenc.StringDatum("TEL", nil, "(404) 555-1213"),
+	}}
+	b := vcardenc.Vcard{Data: []vcardenc.VcardDatum{
+		vcardenc.StringDatum("TEL", nil, "+1 404 555 1212"),
+		vcardenc.StringDatum("TEL", nil, "+1 (404) 555-1213"),
+	}}
+	// Without a region, only numbers written internationally are compared.
+	assert.Len(t, MergeCards(a, b).Data, 3)
+	assert.Len(t, MergeCardsWith(Options{PhoneRegion: "US"}, a, b).Data, 2)
+}
+
+func TestFindOptions(t *testing.T) {
+	assert.Len(t, Find(addressBook, Options{IgnoreUID: true, IgnoreEmail: true, IgnorePhone: true}), 0)
+	clusters := Find(addressBook, Options{IgnoreEmail: true, PhoneRegion: "US"})
+	assert.Len(t, clusters, 1)
+	assert.Equal(t, []int{0, 2}, clusters[0].Indices)
+	// Without a region, "(404) 555-1212" can't be placed.
+	assert.Len(t, Find(addressBook, Options{IgnoreEmail: true}), 0)
+}
+
+func TestCardName(t *testing.T) {
+	assert.Equal(t, "forrest gump", cardName(addressBook[2]))
+}
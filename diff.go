@@ -0,0 +1,217 @@
+package vcardenc
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrPatchConflict is returned by Apply when a change refers to a datum
+// that isn't in the card.
+var ErrPatchConflict = errors.New("Patch does not apply: datum not found in card")
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	// Added changes have only a New datum.
+	Added ChangeKind = "added"
+	// Removed changes have only an Old datum.
+	Removed ChangeKind = "removed"
+	// Changed changes replace Old with New.
+	Changed ChangeKind = "changed"
+)
+
+// Change is one difference between two cards.
+type Change struct {
+	Kind ChangeKind  `json:"kind"`
+	Old  *VcardDatum `json:"old,omitempty"`
+	New  *VcardDatum `json:"new,omitempty"`
+}
+
+// Patch is the list of changes that turns one card into another.
+type Patch []Change
+
+// minDiffSimilarity is how similar the values of two data of the same name
+// but different types must be for Diff to call them a change of one datum.
+const minDiffSimilarity = 0.5
+
+// Diff returns the changes that turn a into b. Data are paired up first
+// when equal, then by sharing a PID, then by name, TYPE and the similarity
+// of their values; paired data that aren't equal are Changed, and the rest
+// are Removed from a or Added from b. Removals and changes come in the
+// order of a, then additions in the order of b.
+func Diff(a, b Vcard) (patch Patch) {
+	a, b = a.SyncData(), b.SyncData()
+	pairs := make([]int, len(a.Data))
+	paired := make([]bool, len(b.Data))
+	for i := range pairs {
+		pairs[i] = -1
+	}
+	matchers := []func(x, y VcardDatum) float64{
+		func(x, y VcardDatum) float64 {
+			if x.Equal(y) {
+				return 1
+			}
+			return 0
+		},
+		func(x, y VcardDatum) float64 {
+			if strings.EqualFold(x.FieldName, y.FieldName) && sharesPID(x, y) {
+				return 1
+			}
+			return 0
+		},
+		diffSimilarity,
+	}
+	for _, match := range matchers {
+		for i, x := range a.Data {
+			if pairs[i] != -1 {
+				continue
+			}
+			best, bestScore := -1, 0.0
+			for j, y := range b.Data {
+				if paired[j] {
+					continue
+				}
+				if score := match(x, y); score > bestScore {
+					best, bestScore = j, score
+				}
+			}
+			if best != -1 {
+				pairs[i], paired[best] = best, true
+			}
+		}
+	}
+	for i, j := range pairs {
+		old := a.Data[i]
+		switch {
+		case j == -1:
+			patch = append(patch, Change{Kind: Removed, Old: &old})
+		case !old.Equal(b.Data[j]):
+			updated := b.Data[j]
+			patch = append(patch, Change{Kind: Changed, Old: &old, New: &updated})
+		}
+	}
+	for j, d := range b.Data {
+		if !paired[j] {
+			added := d
+			patch = append(patch, Change{Kind: Added, New: &added})
+		}
+	}
+	return patch
+}
+
+// diffSimilarity scores two data of the same name as candidates for a
+// change: above zero if they share TYPEs or have similar values.
+func diffSimilarity(x, y VcardDatum) float64 {
+	if !strings.EqualFold(x.FieldName, y.FieldName) {
+		return 0
+	}
+	xv, yv := x, y
+	xv.Attrs, yv.Attrs = nil, nil
+	xout, _ := xv.Output(nil)
+	yout, _ := yv.Output(nil)
+	score := Similarity(xout, yout)
+	if sameTypes(x, y) {
+		return 1 + score
+	}
+	if score < minDiffSimilarity {
+		return 0
+	}
+	return score
+}
+
+func sameTypes(x, y VcardDatum) bool {
+	xt, yt := x.Types(), y.Types()
+	if len(xt) != len(yt) {
+		return false
+	}
+	for _, t := range xt {
+		if !y.HasType(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func sharesPID(x, y VcardDatum) bool {
+	for _, xp := range x.PIDs() {
+		for _, yp := range y.PIDs() {
+			if xp == yp {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply replays a patch onto card, returning the patched card. Removed and
+// Changed data are found by Equal, so a patch applies to any card holding
+// the data it expects regardless of their order.
+func Apply(card Vcard, patch Patch) (Vcard, error) {
+	card = card.SyncData()
+	patched := card.withData(append([]VcardDatum(nil), card.Data...))
+	for _, c := range patch {
+		if c.Kind == Added {
+			if c.New == nil {
+				return Vcard{}, ErrPatchConflict
+			}
+			patched.Data = append(patched.Data, *c.New)
+			continue
+		}
+		if c.Old == nil {
+			return Vcard{}, ErrPatchConflict
+		}
+		i := indexOfDatum(patched.Data, *c.Old)
+		if i == -1 {
+			return Vcard{}, ErrPatchConflict
+		}
+		switch c.Kind {
+		case Removed:
+			patched.Data = append(patched.Data[:i], patched.Data[i+1:]...)
+		case Changed:
+			if c.New == nil {
+				return Vcard{}, ErrPatchConflict
+			}
+			patched.Data[i] = *c.New
+		}
+	}
+	return patched.SyncFields(), nil
+}
+
+func indexOfDatum(data []VcardDatum, d VcardDatum) int {
+	for i, e := range data {
+		if e.Equal(d) {
+			return i
+		}
+	}
+	return -1
+}
+
+// String is a human-readable summary of the patch, one change per line:
+// "+" for added data, "-" for removed and "~" for changed, with the old
+// and new encodings of changed data separated by "=>".
+func (p Patch) String() string {
+	var lines []string
+	for _, c := range p {
+		switch c.Kind {
+		case Added:
+			lines = append(lines, "+ "+datumSummary(c.New))
+		case Removed:
+			lines = append(lines, "- "+datumSummary(c.Old))
+		case Changed:
+			lines = append(lines, "~ "+datumSummary(c.Old)+" => "+datumSummary(c.New))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func datumSummary(d *VcardDatum) string {
+	if d == nil {
+		return "<nil>"
+	}
+	out, err := d.Output(nil)
+	if err != nil {
+		return d.FieldName + ": " + err.Error()
+	}
+	return strings.TrimSuffix(strings.Replace(out, "\n ", "", -1), "\n")
+}
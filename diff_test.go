@@ -0,0 +1,56 @@
+package vcardenc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	diffBefore = Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Forrest Gump"),
+		StringDatum("TEL", AttrMap{"TYPE": []string{"home"}, "PID": []string{"1"}}, "tel:+14045551212"),
+		StringDatum("TEL", AttrMap{"TYPE": []string{"work"}}, "tel:+11115551212"),
+		StringDatum("EMAIL", AttrMap{"TYPE": []string{"work"}, "PREF": []string{"1"}}, "forrestgump@example.com"),
+		StringDatum("NOTE", nil, "Likes shrimp"),
+	}}
+	diffAfter = Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Forrest Gump"),
+		StringDatum("EMAIL", AttrMap{"PREF": []string{"1"}, "TYPE": []string{"work"}}, "forrestgump@example.com"),
+		StringDatum("TEL", AttrMap{"TYPE": []string{"cell"}, "PID": []string{"1"}}, "tel:+14045559999"),
+		StringDatum("TEL", AttrMap{"TYPE": []string{"work"}}, "tel:+11115551313"),
+		StringDatum("URL", nil, "http://www.example.com/"),
+	}}
+)
+
+func TestDiff(t *testing.T) {
+	patch := Diff(diffBefore, diffAfter)
+	assert.Equal(t, `~ TEL;TYPE=home;PID=1:tel:+14045551212 => TEL;TYPE=cell;PID=1:tel:+14045559999
+~ TEL;TYPE=work:tel:+11115551212 => TEL;TYPE=work:tel:+11115551313
+- NOTE:Likes shrimp
++ URL:http://www.example.com/`, patch.String())
+	assert.Empty(t, Diff(diffBefore, diffBefore))
+}
+
+func TestApply(t *testing.T) {
+	patch := Diff(diffBefore, diffAfter)
+	encoded, err := json.Marshal(patch)
+	assert.Nil(t, err)
+	var decoded Patch
+	assert.Nil(t, json.Unmarshal(encoded, &decoded))
+
+	patched, err := Apply(diffBefore, decoded)
+	assert.Nil(t, err)
+	assert.Empty(t, Diff(patched, diffAfter))
+
+	_, err = Apply(diffAfter, decoded)
+	assert.Equal(t, ErrPatchConflict, err)
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity("forrest gump", "forrest gump"))
+	assert.InDelta(t, 0.92, Similarity("forrest gump", "forest gump"), 0.01)
+	assert.Equal(t, 1.0, Similarity("", ""))
+	assert.Equal(t, 0.0, Similarity("abc", "xyz"))
+}
@@ -0,0 +1,79 @@
+package vcardenc
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+// ErrBadEmail is returned when an EMAIL value isn't an email address.
+var ErrBadEmail = errors.New("Malformed email address")
+
+// Email is a parsed EMAIL value.
+type Email struct {
+	// Name is the display name, if the value was written "Name <address>".
+	Name string
+	// Local is the part of the address before the "@".
+	Local string
+	// Domain is the part after the "@", lower case, with internationalized
+	// labels Punycode-encoded.
+	Domain string
+}
+
+// ParseEmail reads an EMAIL value with net/mail, stripping the "mailto:"
+// that some exporters put in front.
+func ParseEmail(value string) (Email, error) {
+	value = strings.TrimSpace(value)
+	if len(value) > 7 && strings.EqualFold(value[:7], "mailto:") {
+		value = value[7:]
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return Email{}, ErrBadEmail
+	}
+	at := strings.LastIndex(addr.Address, "@")
+	if at < 1 || at == len(addr.Address)-1 {
+		return Email{}, ErrBadEmail
+	}
+	return Email{
+		Name:   addr.Name,
+		Local:  addr.Address[:at],
+		Domain: toASCIIDomain(addr.Address[at+1:]),
+	}, nil
+}
+
+// String formats the address, without any display name.
+func (e Email) String() string {
+	return e.Local + "@" + e.Domain
+}
+
+// Key is the address with its local part lower-cased too. Strictly the local
+// part is case-sensitive, but in practice it never is, so this is what to
+// compare addresses on when deduplicating.
+func (e Email) Key() string {
+	return strings.ToLower(e.Local) + "@" + e.Domain
+}
+
+// Equal reports whether two addresses are the same by Key.
+func (e Email) Equal(other Email) bool {
+	return e.Key() == other.Key()
+}
+
+// Email parses the value of an EMAIL datum with ParseEmail.
+func (datum VcardDatum) Email() (Email, error) {
+	return ParseEmail(datum.StringValue)
+}
+
+// validateEmail is the PropertySpec.Validator of EMAIL, which must be a
+// bare address, without the display name or angle brackets ParseEmail
+// tolerates.
+func validateEmail(datum VcardDatum) error {
+	e, err := datum.Email()
+	if err != nil {
+		return err
+	}
+	if e.Name != "" || strings.ContainsAny(datum.StringValue, "<>") {
+		return ErrBadEmail
+	}
+	return nil
+}
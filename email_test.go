@@ -0,0 +1,50 @@
+package vcardenc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	emailTCs = map[string]Email{
+		"forrestgump@example.com":               {"", "forrestgump", "example.com"},
+		"mailto:ForrestGump@Example.COM":        {"", "ForrestGump", "example.com"},
+		"Forrest Gump <forrest@bubba-gump.com>": {"Forrest Gump", "forrest", "bubba-gump.com"},
+		"jenny@Bücher.example":                  {"", "jenny", "xn--bcher-kva.example"},
+		"lt@münchen.de":                         {"", "lt", "xn--mnchen-3ya.de"},
+	}
+	badEmails = []string{"", "forrest", "forrest@", "@example.com", "mailto:"}
+)
+
+func TestParseEmail(t *testing.T) {
+	for value, expected := range emailTCs {
+		e, err := ParseEmail(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, e, value)
+	}
+	for _, value := range badEmails {
+		_, err := ParseEmail(value)
+		assert.Equal(t, ErrBadEmail, err, value)
+	}
+	a, _ := ParseEmail("mailto:ForrestGump@Example.COM")
+	b, _ := ParseEmail("forrestgump@example.com")
+	assert.True(t, a.Equal(b))
+	assert.Equal(t, "ForrestGump@example.com", a.String())
+}
+
+func TestValidateEmail(t *testing.T) {
+	card := Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Forrest Gump"),
+		StringDatum("EMAIL", nil, "forrestgump@example.com"),
+		StringDatum("EMAIL", nil, "not an address"),
+		StringDatum("EMAIL", nil, "Forrest Gump <forrest@bubba-gump.com>"),
+		StringDatum("EMAIL", nil, "<forrest@bubba-gump.com>"),
+	}}
+	errs := DefaultRegistry.Validate(card)
+	assert.Len(t, errs, 3)
+	for _, err := range errs {
+		assert.True(t, errors.Is(err, ErrBadEmail))
+	}
+}
@@ -2,12 +2,45 @@ package vcardenc
 
 import "strings"
 
-func escapedJoin(v []string, delimiter string) string {
+// componentJoin escapes each value of a structured component, commas included,
+// and joins them with commas.
+func componentJoin(values []string) string {
 	var vo []string
-	for _, e := range v {
-		vo = append(vo, escape(e))
+	for _, e := range values {
+		vo = append(vo, escapeComponent(e))
 	}
-	return strings.Join(vo, delimiter)
+	return strings.Join(vo, ",")
+}
+
+// structuredJoin encodes a list of components, each a list of values, as
+// comma-delimited values within semicolon-delimited components.
+func structuredJoin(components [][]string) string {
+	var co []string
+	for _, c := range components {
+		co = append(co, componentJoin(c))
+	}
+	return strings.Join(co, ";")
+}
+
+func flattenComponents(components [][]string) (values []string) {
+	for _, c := range components {
+		values = append(values, c...)
+	}
+	return values
+}
+
+// paramJoin escapes parameter values and joins them with commas, quoting
+// any that would otherwise be cut short by a comma, semicolon or colon.
+func paramJoin(values []string) string {
+	var vo []string
+	for _, v := range values {
+		v = escape(v)
+		if strings.ContainsAny(v, ",;:") {
+			v = `"` + v + `"`
+		}
+		vo = append(vo, v)
+	}
+	return strings.Join(vo, ",")
 }
 
 func escapeQuoted(s string) string {
@@ -40,3 +73,33 @@ func escape(s string) (o string) {
 	//o = strings.Replace(o, ",", "\\,", -1)
 	return o
 }
+
+// escapeComponent is escape, but also escapes commas, which are significant
+// inside structured values.
+func escapeComponent(s string) string {
+	return strings.Replace(escape(s), ",", "\\,", -1)
+}
+
+// unescape reverses backslash escaping; "\n" and "\N" become newlines and any
+// other escaped character is taken literally.
+func unescape(s string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+	var (
+		escaped bool
+		out     []rune
+	)
+	for _, c := range s {
+		if !escaped && c == '\\' {
+			escaped = true
+			continue
+		}
+		if escaped && (c == 'n' || c == 'N') {
+			c = '\n'
+		}
+		out = append(out, c)
+		escaped = false
+	}
+	return string(out)
+}
@@ -0,0 +1,158 @@
+package vcardenc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBadMediaName is returned by a DirStore asked for a name that isn't a
+// plain file name, so that a hostile URI can't reach outside its directory.
+var ErrBadMediaName = errors.New("Media name isn't a plain file name")
+
+// MediaStore keeps media extracted from cards, by name. Names are made by
+// ExtractMedia from the content hash, so Put may skip names it already has.
+type MediaStore interface {
+	Put(name string, data []byte) error
+	Get(name string) ([]byte, error)
+}
+
+// DirStore is a MediaStore keeping each item as a file in a directory.
+type DirStore string
+
+// Put writes data to the named file unless it already exists. The data is
+// written to a temporary file first and renamed into place, so that a
+// crash or a concurrent Put never leaves a partial file under the name.
+func (dir DirStore) Put(name string, data []byte) (err error) {
+	path, err := dir.path(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp(string(dir), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get reads the named file.
+func (dir DirStore) Get(name string) ([]byte, error) {
+	path, err := dir.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func (dir DirStore) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return "", ErrBadMediaName
+	}
+	return filepath.Join(string(dir), name), nil
+}
+
+// mediaProperties are the properties ExtractMedia and InlineMedia handle.
+var mediaProperties = []string{"PHOTO", "LOGO", "SOUND"}
+
+// mediaExtensions name the files of common media types. Anything else is
+// stored as ".bin"; the MEDIATYPE written on the datum is what counts.
+var mediaExtensions = map[string]string{
+	"image/jpeg": ".jpg", "image/png": ".png", "image/gif": ".gif",
+	"image/webp": ".webp", "image/svg+xml": ".svg", "image/tiff": ".tiff",
+	"audio/mpeg": ".mp3", "audio/ogg": ".ogg", "audio/wav": ".wav",
+	"audio/wave": ".wav", "audio/aac": ".aac",
+}
+
+// MediaName is the content-addressed name ExtractMedia stores media under:
+// the SHA-256 of the data in hex, and an extension for the media type.
+func MediaName(m Media) string {
+	sum := sha256.Sum256(m.Data)
+	ext, ok := mediaExtensions[m.MediaType]
+	if !ok {
+		ext = ".bin"
+	}
+	return hex.EncodeToString(sum[:]) + ext
+}
+
+// ExtractMedia returns copies of the cards in which every embedded PHOTO,
+// LOGO and SOUND has been put in store under its MediaName and replaced by
+// a reference to baseURI followed by that name, like
+// "https://media.example.com/" or "file:///var/media/". Identical media in
+// several cards is stored once.
+func ExtractMedia(cards []Vcard, store MediaStore, baseURI string) ([]Vcard, error) {
+	return transformMedia(cards, func(d VcardDatum, m Media, version string) (VcardDatum, error) {
+		if !m.Inline() {
+			return d, nil
+		}
+		name := MediaName(m)
+		if err := store.Put(name, m.Data); err != nil {
+			return d, err
+		}
+		m.Data, m.URI = nil, baseURI+name
+		return MediaDatum(d.FieldName, m, d.Attrs, version), nil
+	})
+}
+
+// InlineMedia is the inverse of ExtractMedia: media referenced under
+// baseURI is read back from store and embedded. Other URIs are left alone.
+func InlineMedia(cards []Vcard, store MediaStore, baseURI string) ([]Vcard, error) {
+	return transformMedia(cards, func(d VcardDatum, m Media, version string) (VcardDatum, error) {
+		if m.Inline() || !strings.HasPrefix(m.URI, baseURI) {
+			return d, nil
+		}
+		data, err := store.Get(m.URI[len(baseURI):])
+		if err != nil {
+			return d, err
+		}
+		m.Data, m.URI = data, ""
+		return MediaDatum(d.FieldName, m, d.Attrs, version), nil
+	})
+}
+
+// transformMedia applies transform to the media data of each card, telling
+// it the version whose form replacements should take: that of the card,
+// or 4.0 for cards that don't say, as Encode writes them as 4.0.
+func transformMedia(cards []Vcard, transform func(VcardDatum, Media, string) (VcardDatum, error)) ([]Vcard, error) {
+	transformed := make([]Vcard, len(cards))
+	for i, v := range cards {
+		version := v.Version
+		if version == "" {
+			version = "4.0"
+		}
+		transformed[i] = v.withData(make([]VcardDatum, len(v.Data)))
+		for j, d := range v.Data {
+			if stringSliceContainsFold(mediaProperties, d.FieldName) {
+				m, err := d.Media()
+				if err != nil {
+					return nil, PropertyError{d.FieldName, err}
+				}
+				if d, err = transform(d, m, version); err != nil {
+					return nil, PropertyError{d.FieldName, err}
+				}
+			}
+			transformed[i].Data[j] = d
+		}
+	}
+	return transformed, nil
+}
@@ -0,0 +1,72 @@
+package vcardenc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractMedia(t *testing.T) {
+	dir := t.TempDir()
+	store := DirStore(dir)
+
+	photo := Media{MediaType: "image/gif", Data: gif}
+	cards := []Vcard{
+		{Version: "4.0", Data: []VcardDatum{
+			StringDatum("FN", nil, "Joe Bloggs"),
+			MediaDatum("PHOTO", photo, AttrMap{"TYPE": []string{"work"}}, "4.0"),
+			StringDatum("LOGO", nil, "https://example.com/logo.png"),
+		}},
+		{Version: "3.0", Data: []VcardDatum{
+			StringDatum("FN", nil, "Jane Bloggs"),
+			MediaDatum("PHOTO", photo, nil, "3.0"),
+		}},
+	}
+	extracted, err := ExtractMedia(cards, store, "file://"+dir+"/")
+	assert.Nil(t, err)
+
+	name := MediaName(photo)
+	assert.Equal(t, ".gif", name[len(name)-4:])
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1, "the same photo is stored once")
+
+	out, err := extracted[0].Data[1].Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "PHOTO;TYPE=work;MEDIATYPE=image/gif:file://"+dir+"/"+name+"\n", wrapFree(out))
+	assert.Equal(t, cards[0].Data[2], extracted[0].Data[2])
+	assert.Equal(t, "file://"+dir+"/"+name, extracted[1].Data[1].StringValue)
+	assert.Equal(t, []string{"uri"}, extracted[1].Data[1].Attrs.Get("VALUE"))
+
+	inlined, err := InlineMedia(extracted, store, "file://"+dir+"/")
+	assert.Nil(t, err)
+	assert.Equal(t, cards, inlined)
+
+	_, err = InlineMedia(extracted, store, "file://")
+	assert.NotNil(t, err, "names with slashes are refused")
+}
+
+func TestDirStorePut(t *testing.T) {
+	dir := t.TempDir()
+	store := DirStore(dir)
+	assert.Nil(t, store.Put("a.bin", []byte("first")))
+	assert.Nil(t, store.Put("a.bin", []byte("second")))
+	data, err := store.Get("a.bin")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("first"), data)
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1, "no temporary files are left behind")
+	info, err := os.Stat(filepath.Join(dir, "a.bin"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	assert.Equal(t, ErrBadMediaName, store.Put("../a.bin", nil))
+}
+
+// wrapFree undoes the line folding of Output.
+func wrapFree(s string) string {
+	return unwrapLines(s)[0] + "\n"
+}
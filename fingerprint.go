@@ -0,0 +1,53 @@
+package vcardenc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// VolatileProperties change without the contact changing, so
+// FingerprintOptions.ExcludeVolatile leaves them out of fingerprints.
+var VolatileProperties = []string{"REV", "PRODID"}
+
+// FingerprintOptions configure Fingerprint.
+type FingerprintOptions struct {
+	// ExcludeVolatile leaves VolatileProperties out of the fingerprint.
+	ExcludeVolatile bool
+
+	// Exclude lists further properties to leave out, ignoring case.
+	Exclude []string
+}
+
+// Fingerprint returns a hex SHA-256 hash of the normalized card, which is
+// the same for cards that differ only in folding, line endings, parameter
+// order, name casing and the other things Normalize smooths over.
+func Fingerprint(v Vcard, opts FingerprintOptions) (string, error) {
+	exclude := opts.Exclude
+	if opts.ExcludeVolatile {
+		// Copied, so as not to write into spare capacity of opts.Exclude.
+		exclude = append(append([]string(nil), opts.Exclude...), VolatileProperties...)
+	}
+	hash := sha256.New()
+	for _, d := range Normalize(v).Data {
+		if stringSliceContainsFold(exclude, d.FieldName) {
+			continue
+		}
+		out, err := d.Output(nil)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(strings.Replace(out, "\n ", "", -1)))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ETag returns a strong HTTP entity tag for the card, quotes included,
+// ignoring VolatileProperties so that an unchanged contact keeps its tag.
+func ETag(v Vcard) (string, error) {
+	fp, err := Fingerprint(v, FingerprintOptions{ExcludeVolatile: true})
+	if err != nil {
+		return "", err
+	}
+	return `"` + fp + `"`, nil
+}
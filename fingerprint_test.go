@@ -0,0 +1,43 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	folded := "BEGIN:VCARD\r\nVERSION:4.0\r\nfn:Forrest Gump\r\nTEL;VALUE=uri;TYPE=home,voice:tel:+140455\r\n 51212\r\nREV:20080424T195243Z\r\nEND:VCARD\r\n"
+	unfolded := "BEGIN:VCARD\nVERSION:4.0\nREV:20160101T000000Z\nTEL;TYPE=voice,home;VALUE=uri:tel:+14045551212\nFN:Forrest Gump\nEND:VCARD\n"
+	a, err := ParseVcard(folded, nil)
+	assert.Nil(t, err)
+	b, err := ParseVcard(unfolded, nil)
+	assert.Nil(t, err)
+
+	fa, err := Fingerprint(a, FingerprintOptions{})
+	assert.Nil(t, err)
+	fb, err := Fingerprint(b, FingerprintOptions{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, fa, fb, "REV differs")
+	assert.Len(t, fa, 64)
+
+	ea, err := ETag(a)
+	assert.Nil(t, err)
+	eb, err := ETag(b)
+	assert.Nil(t, err)
+	assert.Equal(t, ea, eb)
+
+	fa, _ = Fingerprint(a, FingerprintOptions{Exclude: []string{"rev"}})
+	fb, _ = Fingerprint(b, FingerprintOptions{Exclude: []string{"rev"}})
+	assert.Equal(t, fa, fb)
+
+	// The caller's Exclude isn't written into.
+	exclude := make([]string, 1, 4)
+	exclude[0] = "NOTE"
+	Fingerprint(a, FingerprintOptions{ExcludeVolatile: true, Exclude: exclude})
+	assert.Equal(t, []string{"NOTE", "", ""}, exclude[:3])
+
+	b.Data = append(b.Data, StringDatum("NOTE", nil, "Changed"))
+	eb, _ = ETag(b)
+	assert.NotEqual(t, ea, eb)
+}
@@ -0,0 +1,112 @@
+package vcardenc
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrBadSex is returned when the sex component of a GENDER value isn't
+	// one of those RFC 6350 lists. Unlike most enumerations in vCard, it
+	// can't be extended.
+	ErrBadSex = errors.New("Unknown sex in GENDER")
+
+	// ErrBadGramGender is returned when a GRAMGENDER value isn't a token,
+	// so is neither one registered with IANA nor an x-name.
+	ErrBadGramGender = errors.New("Malformed grammatical gender")
+)
+
+// Sex is the first component of a GENDER value.
+type Sex string
+
+// Sexes from RFC 6350 section 6.2.7. SexUnspecified is an empty component,
+// for a GENDER that only gives an identity.
+const (
+	SexUnspecified Sex = ""
+	SexMale        Sex = "M"
+	SexFemale      Sex = "F"
+	SexOther       Sex = "O"
+	SexNone        Sex = "N"
+	SexUnknown     Sex = "U"
+)
+
+var sexes = []string{"", "M", "F", "O", "N", "U"}
+
+// ParseSex reads the sex component of a GENDER value, ignoring case.
+func ParseSex(s string) (Sex, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if !stringSliceContains(sexes, s) {
+		return "", ErrBadSex
+	}
+	return Sex(s), nil
+}
+
+// String returns the sex as written in a GENDER value.
+func (s Sex) String() string {
+	return string(s)
+}
+
+// Gender is a GENDER value: a sex, and free text for gender identity.
+type Gender struct {
+	Sex      Sex    `json:"sex"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// Gender reads the components of a GENDER datum.
+func (datum VcardDatum) Gender() (Gender, error) {
+	sex, err := ParseSex(strings.Join(datum.Component(0), ","))
+	if err != nil {
+		return Gender{}, err
+	}
+	return Gender{sex, strings.Join(datum.Component(1), ",")}, nil
+}
+
+// GenderDatum makes a GENDER datum, leaving out the identity component if
+// it's empty.
+func GenderDatum(g Gender) VcardDatum {
+	if g.Identity == "" {
+		return SemicolonStructuredDatum("GENDER", nil, g.Sex.String())
+	}
+	return SemicolonStructuredDatum("GENDER", nil, g.Sex.String(), g.Identity)
+}
+
+func validateGender(datum VcardDatum) error {
+	_, err := datum.Gender()
+	return err
+}
+
+// GramGender is a GRAMGENDER value, the grammatical gender to address
+// the contact with.
+type GramGender string
+
+// Grammatical genders from RFC 9554 section 3.2. They are untyped, so
+// they can be compared to a GramGender or to a plain string value.
+const (
+	GramGenderAnimate   = "animate"
+	GramGenderCommon    = "common"
+	GramGenderFeminine  = "feminine"
+	GramGenderInanimate = "inanimate"
+	GramGenderMasculine = "masculine"
+	GramGenderNeuter    = "neuter"
+)
+
+// ParseGramGender reads a GRAMGENDER value, ignoring case. Genders it
+// doesn't know are returned as they are, as long as they're well-formed
+// tokens.
+func ParseGramGender(s string) (GramGender, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !isToken(s) {
+		return "", ErrBadGramGender
+	}
+	return GramGender(s), nil
+}
+
+// String returns the grammatical gender as written in a GRAMGENDER value.
+func (g GramGender) String() string {
+	return string(g)
+}
+
+func validateGramGender(datum VcardDatum) error {
+	_, err := ParseGramGender(datum.StringValue)
+	return err
+}
@@ -0,0 +1,51 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var genderTCs = map[string]Gender{
+	"GENDER:M":                 {SexMale, ""},
+	"GENDER:f":                 {SexFemale, ""},
+	"GENDER:O;intersex":        {SexOther, "intersex"},
+	"GENDER:;it's complicated": {SexUnspecified, "it's complicated"},
+	"GENDER:U":                 {SexUnknown, ""},
+}
+
+func TestGender(t *testing.T) {
+	for line, expected := range genderTCs {
+		d, err := ParseDatumLine(line)
+		assert.Nil(t, err, line)
+		g, err := d.Gender()
+		assert.Nil(t, err, line)
+		assert.Equal(t, expected, g, line)
+		again, err := GenderDatum(g).Gender()
+		assert.Nil(t, err, line)
+		assert.Equal(t, g, again, line)
+	}
+	d, err := ParseDatumLine("GENDER:X;robot")
+	assert.Nil(t, err)
+	_, err = d.Gender()
+	assert.Equal(t, ErrBadSex, err)
+
+	card := Vcard{Data: []VcardDatum{StringDatum("FN", nil, "Joe"), d}}
+	assert.Nil(t, card.SyncFields().Gender)
+	assert.Equal(t, []error{PropertyError{"GENDER", ErrBadSex}}, DefaultRegistry.Validate(card))
+}
+
+func TestParseGramGender(t *testing.T) {
+	g, err := ParseGramGender("Neuter")
+	assert.Nil(t, err)
+	assert.Equal(t, GramGender(GramGenderNeuter), g)
+	assert.True(t, g == GramGenderNeuter)
+	g, err = ParseGramGender("x-dual")
+	assert.Nil(t, err)
+	assert.Equal(t, GramGender("x-dual"), g)
+	g, err = ParseGramGender("Dual")
+	assert.Nil(t, err)
+	assert.Equal(t, GramGender("dual"), g)
+	_, err = ParseGramGender("plural?")
+	assert.Equal(t, ErrBadGramGender, err)
+}
@@ -0,0 +1,144 @@
+package vcardenc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrBadGeo is returned when a GEO value is neither a geo: URI nor a 3.0
+// "lat;lon" pair, or lies off the face of the earth.
+var ErrBadGeo = errors.New("Malformed geographic position")
+
+// Geo is a GEO value: a WGS 84 position as in RFC 5870.
+type Geo struct {
+	// Latitude and Longitude are in decimal degrees.
+	Latitude, Longitude float64
+	// Altitude is in metres, or zero if not given.
+	Altitude float64
+	// Uncertainty is the radius of uncertainty in metres, or zero if
+	// unknown.
+	Uncertainty float64
+}
+
+// ParseGeo reads a geo: URI like "geo:37.386013,-122.082932;u=35", or the
+// "37.386013;-122.082932" of vCard 3.0.
+func ParseGeo(value string) (Geo, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 4 || !strings.EqualFold(value[:4], "geo:") {
+		return parseGeoCoordinates(strings.Replace(value, ";", ",", 1))
+	}
+	params := strings.Split(value[4:], ";")
+	g, err := parseGeoCoordinates(params[0])
+	if err != nil {
+		return Geo{}, err
+	}
+	for _, param := range params[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return Geo{}, ErrBadGeo
+		}
+		switch strings.ToLower(kv[0]) {
+		case "crs":
+			// Other reference systems would need reprojecting.
+			if !strings.EqualFold(kv[1], "wgs84") {
+				return Geo{}, ErrBadGeo
+			}
+		case "u":
+			if g.Uncertainty, err = parseDecimal(kv[1]); err != nil || g.Uncertainty < 0 {
+				return Geo{}, ErrBadGeo
+			}
+		}
+	}
+	return g, nil
+}
+
+func parseGeoCoordinates(s string) (g Geo, err error) {
+	coords := strings.Split(s, ",")
+	if len(coords) < 2 || len(coords) > 3 {
+		return Geo{}, ErrBadGeo
+	}
+	values := make([]float64, len(coords))
+	for i, c := range coords {
+		if values[i], err = parseDecimal(strings.TrimSpace(c)); err != nil {
+			return Geo{}, ErrBadGeo
+		}
+	}
+	g.Latitude, g.Longitude = values[0], values[1]
+	if len(values) == 3 {
+		g.Altitude = values[2]
+	}
+	if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 {
+		return Geo{}, ErrBadGeo
+	}
+	return g, nil
+}
+
+// parseDecimal reads a number as RFC 5870 writes them, digits with an
+// optional sign and fraction, refusing the NaN, infinities, exponents and
+// hex that strconv.ParseFloat would let through.
+func parseDecimal(s string) (float64, error) {
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return 0, ErrBadGeo
+	}
+	whole, fraction, hasPoint := strings.Cut(digits, ".")
+	if !isDigits(whole) || (hasPoint && !isDigits(fraction)) {
+		return 0, ErrBadGeo
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// isDigits reports whether s is one or more ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// URI formats the position as a geo: URI.
+func (g Geo) URI() string {
+	uri := "geo:" + formatFloat(g.Latitude) + "," + formatFloat(g.Longitude)
+	if g.Altitude != 0 {
+		uri += "," + formatFloat(g.Altitude)
+	}
+	if g.Uncertainty != 0 {
+		uri += ";u=" + formatFloat(g.Uncertainty)
+	}
+	return uri
+}
+
+// String formats the position as vCard 3.0 does, "lat;lon".
+func (g Geo) String() string {
+	return formatFloat(g.Latitude) + ";" + formatFloat(g.Longitude)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Geo parses the value of a GEO datum, or the GEO parameter of an ADR,
+// with ParseGeo.
+func (datum VcardDatum) Geo() (Geo, error) {
+	if strings.EqualFold(datum.FieldName, "ADR") {
+		return ParseGeo(datum.GeoParam())
+	}
+	return ParseGeo(datum.StringValue)
+}
+
+// GeoDatum makes a GEO datum for the position in the form version expects:
+// a geo: URI for "4.0", or otherwise "lat;lon", which loses altitude and
+// uncertainty. attrs is copied, not changed.
+func GeoDatum(g Geo, attrs AttrMap, version string) VcardDatum {
+	d := StringDatum("GEO", copyAttrs(attrs), g.URI())
+	if version != "4.0" {
+		d.StringValue = g.String()
+	}
+	return d
+}
@@ -0,0 +1,46 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	geoTCs = map[string]Geo{
+		"geo:37.386013,-122.082932":           {37.386013, -122.082932, 0, 0},
+		"GEO:48.2010,16.3695,183;u=40":        {48.201, 16.3695, 183, 40},
+		"geo:-33.8688,151.2093;crs=WGS84;x=y": {-33.8688, 151.2093, 0, 0},
+		"37.386013;-122.082932":               {37.386013, -122.082932, 0, 0},
+		" 53.3498 , -6.2603 ":                 {53.3498, -6.2603, 0, 0},
+	}
+	badGeos = []string{
+		"", "geo:", "geo:91,0", "geo:0,181", "geo:1,2;crs=nad27", "geo:1,2;u=-1", "here", "1;2;3;4",
+		"geo:NaN,NaN", "geo:1,2;u=NaN", "geo:1,2,NaN", "NaN;NaN", "geo:Inf,0", "geo:0,-Inf", "geo:1,2;u=+Inf",
+		"geo:0x1p3,0", "geo:1e1,0", "geo:1.,0", "geo:.5,0", "geo:--1,0", "geo:1,2;u=",
+	}
+)
+
+func TestParseGeo(t *testing.T) {
+	for s, expected := range geoTCs {
+		g, err := ParseGeo(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, g, s)
+	}
+	for _, s := range badGeos {
+		_, err := ParseGeo(s)
+		assert.Equal(t, ErrBadGeo, err, s)
+	}
+}
+
+func TestGeoDatum(t *testing.T) {
+	g := Geo{Latitude: 48.201, Longitude: 16.3695, Uncertainty: 40}
+	assert.Equal(t, "geo:48.201,16.3695;u=40", GeoDatum(g, nil, "4.0").StringValue)
+	assert.Equal(t, "48.201;16.3695", GeoDatum(g, nil, "3.0").StringValue)
+
+	adr, err := ParseDatumLine(`ADR;GEO="geo:12.3457,78.910":;;Main St;Anytown;;;`)
+	assert.Nil(t, err)
+	g, err = adr.Geo()
+	assert.Nil(t, err)
+	assert.Equal(t, Geo{Latitude: 12.3457, Longitude: 78.91}, g)
+}
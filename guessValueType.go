@@ -1,26 +1,16 @@
 package vcardenc
 
-var (
-	// Field names on which to guess value type, bearing in mind this is vCard 4.0
-	// and that I'm largely working from wikipedia to avoid headaches.
-	knownSemicolonStructuredFields = []string{"ADR", "N"}
-	knownCommaStructuredFields     = []string{"NICKNAME"}
-	knownBase64Fields              = []string{}
-)
-
 // guess and return a valueType. Default in case of stupid is StringValueType
 // TODO: this should look for v4.0 style type hints in attrs, to disambiguate
 // fieldNames that can have URI, data-URI, or raw base64 datatypes.
-func guessValueType(fieldName string, attrs AttrMap, rawValue string) valueType {
-	if stringSliceContains(knownSemicolonStructuredFields, fieldName) {
-		return SemicolonStructuredValueType
-	}
-	if stringSliceContains(knownCommaStructuredFields, fieldName) {
-		return CommaStructuredValueType
-	}
-	if stringSliceContains(knownBase64Fields, fieldName) {
+func (r *Registry) guessValueType(fieldName string, attrs AttrMap, rawValue string) valueType {
+	// vCard 2.1 and 3.0 mark inline base64 media with ENCODING.
+	if isBase64Encoding(attrs.first("ENCODING")) {
 		return BinaryValueType
 	}
+	if spec, ok := r.Lookup(fieldName); ok && spec.ValueType != "" {
+		return spec.ValueType
+	}
 	// TODO: Unfinished
 	return StringValueType
 }
@@ -0,0 +1,63 @@
+package vcardenc
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrBadKind is returned when a KIND value isn't a token, so is neither a
+// kind registered with IANA nor an x-name.
+var ErrBadKind = errors.New("Malformed kind of object")
+
+// Kind is the KIND of object a card represents.
+type Kind string
+
+// Kinds from RFC 6350 section 6.1.4, and RFC 6473 for applications. Others
+// may be registered, like RFC 6869's "device".
+const (
+	KindIndividual  Kind = "individual"
+	KindGroup       Kind = "group"
+	KindOrg         Kind = "org"
+	KindLocation    Kind = "location"
+	KindApplication Kind = "application"
+)
+
+// ParseKind reads a KIND value, ignoring case. Kinds it doesn't know are
+// returned as they are, as long as they're well-formed tokens like "device"
+// or "x-robot".
+func ParseKind(s string) (Kind, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !isToken(s) {
+		return "", ErrBadKind
+	}
+	return Kind(s), nil
+}
+
+// String returns the kind as written in a KIND value.
+func (k Kind) String() string {
+	return string(k)
+}
+
+// kind returns the card's Kind, or if that's empty its KIND, which is
+// KindIndividual if it has none or it's malformed.
+func (v Vcard) kind() Kind {
+	if v.Kind != "" {
+		return v.Kind
+	}
+	if d, ok := v.Get("KIND"); ok {
+		if k, err := ParseKind(d.StringValue); err == nil {
+			return k
+		}
+	}
+	return KindIndividual
+}
+
+// KindDatum makes a KIND datum.
+func KindDatum(k Kind) VcardDatum {
+	return StringDatum("KIND", nil, k.String())
+}
+
+func validateKind(datum VcardDatum) error {
+	_, err := ParseKind(datum.StringValue)
+	return err
+}
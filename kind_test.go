@@ -0,0 +1,42 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKind(t *testing.T) {
+	for s, expected := range map[string]Kind{"Group": KindGroup, " org ": KindOrg, "x-Robot": "x-robot", "application": KindApplication, "Device": "device"} {
+		k, err := ParseKind(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, k, s)
+	}
+	for _, s := range []string{"", "x-ro bot", "robot!", "robot_arm"} {
+		_, err := ParseKind(s)
+		assert.Equal(t, ErrBadKind, err, s)
+	}
+}
+
+func TestVcardKind(t *testing.T) {
+	assert.Equal(t, KindIndividual, Vcard{}.kind())
+	card := Vcard{Data: []VcardDatum{StringDatum("FN", nil, "Acme"), KindDatum(KindOrg)}}
+	assert.Equal(t, KindOrg, card.kind())
+	assert.Equal(t, KindOrg, card.SyncFields().Kind)
+	assert.False(t, IsGroup(card))
+	assert.Empty(t, DefaultRegistry.Validate(card))
+
+	card.Data[1].StringValue = "device"
+	assert.Equal(t, Kind("device"), card.kind())
+	assert.Empty(t, DefaultRegistry.Validate(card))
+
+	card.Data[1].StringValue = "ro bot"
+	assert.Equal(t, KindIndividual, card.kind())
+	assert.Equal(t, Kind(""), card.SyncFields().Kind)
+	assert.Equal(t, []error{PropertyError{"KIND", ErrBadKind}}, DefaultRegistry.Validate(card))
+
+	card.Kind = KindGroup
+	assert.Equal(t, KindGroup, card.kind())
+	assert.True(t, IsGroup(card))
+	assert.Empty(t, DefaultRegistry.Validate(card))
+}
@@ -0,0 +1,193 @@
+package vcardenc
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	// ErrBadDataURI is returned when a data: URI is malformed.
+	ErrBadDataURI = errors.New("Malformed data: URI")
+
+	// ErrNotMedia is returned when asking for the Media of a structured datum.
+	ErrNotMedia = errors.New("Datum can't hold media")
+)
+
+// Media is the value of a PHOTO, LOGO, SOUND or KEY, whichever way the card
+// carried it: as a 4.0 data: URI, as 3.0 base64 with ENCODING=b, or by
+// reference to an external URI.
+type Media struct {
+	// MediaType is the MIME type, like "image/jpeg", or "" if unknown.
+	MediaType string
+	// Data is the content of inline media, and nil for external media.
+	Data []byte
+	// URI is the location of external media, and "" for inline media.
+	URI string
+}
+
+// Inline reports whether the media is carried in the card itself.
+func (m Media) Inline() bool {
+	return m.URI == ""
+}
+
+// DataURI formats inline media as a base64 data: URI, writing any
+// parameters of the media type as RFC 2397 does, like ";charset=utf-8".
+func (m Media) DataURI() string {
+	parts := strings.Split(m.MediaType, ";")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return "data:" + strings.Join(parts, ";") + ";base64," + base64.StdEncoding.EncodeToString(m.Data)
+}
+
+// ParseDataURI reads a data: URI as in RFC 2397, base64 or percent-encoded.
+func ParseDataURI(uri string) (Media, error) {
+	if len(uri) < 5 || !strings.EqualFold(uri[:5], "data:") {
+		return Media{}, ErrBadDataURI
+	}
+	comma := strings.IndexRune(uri, ',')
+	if comma == -1 {
+		return Media{}, ErrBadDataURI
+	}
+	header, payload := uri[5:comma], uri[comma+1:]
+	var m Media
+	if isBase64 := strings.HasSuffix(strings.ToLower(header), ";base64"); isBase64 {
+		header = header[:len(header)-7]
+		data, err := base64.StdEncoding.DecodeString(stripSpace(payload))
+		if err != nil {
+			return Media{}, ErrBadDataURI
+		}
+		m.Data = data
+	} else {
+		data, err := url.PathUnescape(payload)
+		if err != nil {
+			return Media{}, ErrBadDataURI
+		}
+		m.Data = []byte(data)
+	}
+	// RFC 2397 leaves the media type optional, but not the data.
+	if m.Data == nil {
+		m.Data = []byte{}
+	}
+	m.MediaType = strings.ToLower(header)
+	return m, nil
+}
+
+// Media reads the value of a PHOTO, LOGO, SOUND or KEY datum. The media
+// type is taken from the data: URI, the MEDIATYPE parameter, or a 3.0
+// TYPE like "JPEG", in that order, and failing those is sniffed from
+// inline data with http.DetectContentType.
+func (datum VcardDatum) Media() (m Media, err error) {
+	switch {
+	case datum.ValueType == BinaryValueType:
+		m.Data = datum.BinaryValue
+	case datum.ValueType != StringValueType:
+		return Media{}, ErrNotMedia
+	case isBase64Encoding(datum.Attrs.first("ENCODING")):
+		if m.Data, err = base64.StdEncoding.DecodeString(stripSpace(datum.StringValue)); err != nil {
+			return Media{}, err
+		}
+	case len(datum.StringValue) >= 5 && strings.EqualFold(datum.StringValue[:5], "data:"):
+		if m, err = ParseDataURI(datum.StringValue); err != nil {
+			return Media{}, err
+		}
+	default:
+		m.URI = datum.StringValue
+	}
+	if m.MediaType == "" {
+		m.MediaType = strings.ToLower(datum.MediaType())
+	}
+	for _, t := range datum.Attrs.Get("TYPE") {
+		if m.MediaType == "" && stringSliceContains(legacyMediaTypes, strings.ToUpper(t)) {
+			m.MediaType = legacyMediaType(datum.FieldName, t)
+		}
+	}
+	if m.MediaType == "" && m.Inline() {
+		m.MediaType = http.DetectContentType(m.Data)
+	}
+	return m, nil
+}
+
+// MediaDatum makes a datum holding the media in the form version expects:
+// a data: URI for "4.0", or otherwise base64 with ENCODING=b and a TYPE
+// like "JPEG". attrs is copied, not changed, dropping parameters that
+// described the media in some other form.
+func MediaDatum(fieldName string, m Media, attrs AttrMap, version string) VcardDatum {
+	d := StringDatum(fieldName, copyAttrs(attrs), m.URI)
+	for _, t := range d.Attrs.Get("TYPE") {
+		if legacyMediaType(fieldName, t) == m.MediaType {
+			d.SetTypes(removeFold(d.Attrs.Get("TYPE"), t)...)
+		}
+	}
+	d.setParam("ENCODING")
+	d.setParam("MEDIATYPE")
+	d.setParam("VALUE")
+	switch {
+	case version == "4.0" && m.Inline():
+		d.StringValue = m.DataURI()
+	case version == "4.0":
+		d.SetMediaType(m.MediaType)
+	case m.Inline():
+		d.ValueType, d.StringValue, d.BinaryValue = BinaryValueType, "", m.Data
+		d.setParam("ENCODING", "b")
+		d.addLegacyType(m.MediaType)
+	default:
+		d.setParam("VALUE", "uri")
+		d.addLegacyType(m.MediaType)
+	}
+	return d
+}
+
+// convertMedia rewrites inline media in the form version expects, as
+// MediaDatum makes it, leaving external media and anything it can't read
+// alone.
+func convertMedia(d *VcardDatum, version string) {
+	switch strings.ToUpper(d.FieldName) {
+	case "PHOTO", "LOGO", "SOUND", "KEY":
+	default:
+		return
+	}
+	dataURI := d.ValueType == StringValueType && len(d.StringValue) >= 5 && strings.EqualFold(d.StringValue[:5], "data:")
+	legacy := d.ValueType == BinaryValueType || isBase64Encoding(d.Attrs.first("ENCODING"))
+	if (version == "4.0" && !legacy) || (version != "4.0" && !dataURI) {
+		return
+	}
+	m, err := d.Media()
+	if err != nil || !m.Inline() {
+		return
+	}
+	converted := MediaDatum(d.FieldName, m, d.Attrs, version)
+	converted.FieldName, converted.Spelling = d.FieldName, d.Spelling
+	*d = converted
+}
+
+// addLegacyType adds the 3.0 TYPE for a media type, like "JPEG" for
+// "image/jpeg", if it has one, taking the first of legacyMediaTypes where
+// several fit.
+func (datum *VcardDatum) addLegacyType(mediaType string) {
+	for _, t := range legacyMediaTypes {
+		if legacyMediaType(datum.FieldName, t) == mediaType {
+			datum.AddType(t)
+			return
+		}
+	}
+}
+
+// legacyMediaTypes are the 3.0 TYPE values that name media types, from
+// the IANA registrations that RFC 2426 points to, those preferred for
+// writing first.
+var legacyMediaTypes = []string{
+	"GIF", "JPEG", "PNG", "TIFF", "BMP", "WEBP", "SVG+XML", "WAVE", "WAV",
+	"MP3", "MPEG", "OGG", "AAC", "PCM", "AIFF", "PGP", "X509",
+}
+
+func isBase64Encoding(encoding string) bool {
+	return strings.EqualFold(encoding, "b") || strings.EqualFold(encoding, "base64")
+}
+
+func stripSpace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
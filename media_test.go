@@ -0,0 +1,94 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gif is the smallest GIF there is, or near enough.
+var gif = []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")
+
+var mediaTCs = map[string]Media{
+	"PHOTO:data:image/gif;base64,R0lGODlhAQABAAAAADs=":          {"image/gif", gif, ""},
+	"PHOTO;ENCODING=b;TYPE=GIF:R0lGODlhAQABAAAAADs=":            {"image/gif", gif, ""},
+	"PHOTO;ENCODING=BASE64:R0lGODlhAQ ABAAAAADs=":               {"image/gif", gif, ""},
+	"LOGO;MEDIATYPE=image/png:https://example.com/logo.png":     {"image/png", nil, "https://example.com/logo.png"},
+	"KEY:data:,hello%20world":                                   {"text/plain; charset=utf-8", []byte("hello world"), ""},
+	"KEY;ENCODING=b;TYPE=PGP:aGVsbG8=":                          {"application/pgp-keys", []byte("hello"), ""},
+	"SOUND;VALUE=uri;TYPE=work:http://example.com/hello.ogg":    {"", nil, "http://example.com/hello.ogg"},
+	"SOUND;VALUE=uri;TYPE=MP3:http://example.com/hello.mp3":     {"audio/mpeg", nil, "http://example.com/hello.mp3"},
+	"PHOTO;MEDIATYPE=image/jpeg:data:image/gif;base64,R0lGODlh": {"image/gif", []byte("GIF89a"), ""},
+}
+
+func TestMedia(t *testing.T) {
+	for line, expected := range mediaTCs {
+		d, err := ParseDatumLine(line)
+		assert.Nil(t, err, line)
+		m, err := d.Media()
+		assert.Nil(t, err, line)
+		assert.Equal(t, expected, m, line)
+	}
+	_, err := ParseDataURI("data:image/gif;base64,!!!")
+	assert.Equal(t, ErrBadDataURI, err)
+}
+
+func TestMediaSniffing(t *testing.T) {
+	d := StringDatum("PHOTO", nil, "data:;base64,iVBORw0KGgo=")
+	m, err := d.Media()
+	assert.Nil(t, err)
+	assert.Equal(t, "image/png", m.MediaType)
+}
+
+func TestMediaDatum(t *testing.T) {
+	d, err := ParseDatumLine("PHOTO;ENCODING=b;TYPE=GIF,work:R0lGODlhAQABAAAAADs=")
+	assert.Nil(t, err)
+	assert.Equal(t, BinaryValueType, d.ValueType)
+	m, err := d.Media()
+	assert.Nil(t, err)
+
+	out, err := MediaDatum("PHOTO", m, d.Attrs, "4.0").Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "PHOTO;TYPE=work:data:image/gif;base64,R0lGODlhAQABAAAAADs=\n", out)
+
+	out, err = MediaDatum("PHOTO", m, nil, "3.0").Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "PHOTO;TYPE=GIF;ENCODING=b:R0lGODlhAQABAAAAADs=\n", out)
+
+	external := Media{MediaType: "image/png", URI: "https://example.com/me.png"}
+	out, err = MediaDatum("PHOTO", external, nil, "4.0").Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "PHOTO;MEDIATYPE=image/png:https://example.com/me.png\n", out)
+	out, err = MediaDatum("PHOTO", external, nil, "3.0").Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "PHOTO;VALUE=uri;TYPE=PNG:https://example.com/me.png\n", out)
+
+	mp3 := Media{MediaType: "audio/mpeg", URI: "https://example.com/hello.mp3"}
+	for i := 0; i < 10; i++ {
+		out, err = MediaDatum("SOUND", mp3, nil, "3.0").Output(nil)
+		assert.Nil(t, err)
+		assert.Equal(t, "SOUND;VALUE=uri;TYPE=MP3:https://example.com/hello.mp3\n", out)
+	}
+
+	// Sniffed media type parameters are written as RFC 2397 has them.
+	text, err := StringDatum("KEY", nil, "data:,hello").Media()
+	assert.Nil(t, err)
+	assert.Equal(t, "data:text/plain;charset=utf-8;base64,aGVsbG8=", text.DataURI())
+}
+
+func TestEncodeConvertsMedia(t *testing.T) {
+	card := Vcard{Version: "3.0", Data: []VcardDatum{
+		StringDatum("PHOTO", AttrMap{"TYPE": []string{"work"}}, "data:image/gif;base64,R0lGODlhAQABAAAAADs="),
+		StringDatum("LOGO", AttrMap{"VALUE": []string{"uri"}}, "https://example.com/logo.png"),
+	}}
+	out, err := card.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:3.0\nPHOTO;TYPE=work,GIF;ENCODING=b:R0lGODlhAQABAAAAADs=\nLOGO;VALUE=uri:https://example.com/logo.png\nEND:VCARD", out)
+
+	parsed, err := ParseVcard(out, nil)
+	assert.Nil(t, err)
+	parsed.Version = "4.0"
+	out, err = parsed.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nPHOTO;TYPE=work:data:image/gif;base64,R0lGODlhAQABAAAAADs=\nLOGO;VALUE=uri:https://example.com/logo.png\nEND:VCARD", out)
+}
@@ -0,0 +1,257 @@
+package vcardenc
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrBadClientPIDMap is returned when a CLIENTPIDMAP isn't a source
+	// number followed by a URI.
+	ErrBadClientPIDMap = errors.New("Malformed CLIENTPIDMAP value")
+
+	// ErrUIDMismatch is returned by Merge when the cards have different UIDs,
+	// and so by RFC 6350 section 7.1.2 aren't versions of the same contact.
+	ErrUIDMismatch = errors.New("Cannot merge cards with different UIDs")
+)
+
+// ClientPIDMap is a CLIENTPIDMAP property, mapping the Source number used in
+// PID parameters of a card to the URI identifying the client that made them.
+type ClientPIDMap struct {
+	Source int
+	URI    string
+}
+
+// ParseClientPIDMap reads a CLIENTPIDMAP datum.
+func ParseClientPIDMap(datum VcardDatum) (ClientPIDMap, error) {
+	if len(datum.StructuredValue) != 2 || len(datum.StructuredValue[0]) != 1 || len(datum.StructuredValue[1]) != 1 {
+		return ClientPIDMap{}, ErrBadClientPIDMap
+	}
+	source, err := strconv.Atoi(datum.StructuredValue[0][0])
+	if err != nil || source < 1 || datum.StructuredValue[1][0] == "" {
+		return ClientPIDMap{}, ErrBadClientPIDMap
+	}
+	return ClientPIDMap{Source: source, URI: datum.StructuredValue[1][0]}, nil
+}
+
+// Datum returns the CLIENTPIDMAP datum for the mapping.
+func (m ClientPIDMap) Datum() VcardDatum {
+	return SemicolonStructuredDatum("CLIENTPIDMAP", nil, strconv.Itoa(m.Source), m.URI)
+}
+
+// ClientPIDMaps returns the source URIs of the card's CLIENTPIDMAPs, keyed by
+// Source number. Malformed CLIENTPIDMAPs are skipped.
+func (v Vcard) ClientPIDMaps() map[int]string {
+	maps := make(map[int]string)
+	for _, d := range v.GetAll("CLIENTPIDMAP") {
+		if m, err := ParseClientPIDMap(d); err == nil {
+			maps[m.Source] = m.URI
+		}
+	}
+	return maps
+}
+
+// mergeEntry is one property across the three versions given to Merge.
+type mergeEntry struct {
+	versions [3]*VcardDatum
+}
+
+// pidRemapper renumbers the PID sources of several cards onto one shared
+// set of CLIENTPIDMAPs, matching sources by their URI.
+type pidRemapper struct {
+	uris []string
+}
+
+func (r *pidRemapper) source(uri string) int {
+	for i, u := range r.uris {
+		if u == uri {
+			return i + 1
+		}
+	}
+	r.uris = append(r.uris, uri)
+	return len(r.uris)
+}
+
+// remap returns the data of v other than CLIENTPIDMAPs, with PID sources
+// renumbered. PIDs referring to a missing CLIENTPIDMAP are left alone.
+func (r *pidRemapper) remap(v Vcard) (data []VcardDatum) {
+	maps := v.ClientPIDMaps()
+	for _, d := range v.Data {
+		if strings.EqualFold(d.FieldName, "CLIENTPIDMAP") {
+			continue
+		}
+		pids := d.PIDs()
+		if len(pids) > 0 {
+			for i, pid := range pids {
+				if uri, ok := maps[pid.Source]; ok {
+					pids[i].Source = r.source(uri)
+				}
+			}
+			d.Attrs = copyAttrs(d.Attrs)
+			d.SetPIDs(pids...)
+		}
+		data = append(data, d)
+	}
+	return data
+}
+
+// Merge reconciles two edited versions a and b of a common base card, as
+// RFC 6350 section 7 intends: properties are matched across versions by
+// PID, with CLIENTPIDMAP sources compared by URI, or by name and value if
+// they have no PID. Properties that may only appear once, like N or BDAY,
+// are matched by name alone. A property changed in one version and
+// untouched in the other takes the change, and one deleted in either
+// version is dropped unless the other changed it. When both changed it,
+// b wins. Properties added in either are kept once, so adding the same
+// EMAIL on two devices doesn't duplicate it. Pass an empty base for cards
+// with no known common ancestor.
+func Merge(base, a, b Vcard) (Vcard, error) {
+	base, a, b = base.SyncData(), a.SyncData(), b.SyncData()
+	uid := ""
+	for _, v := range []Vcard{base, a, b} {
+		if d, ok := v.Get("UID"); ok {
+			if uid != "" && d.StringValue != uid {
+				return Vcard{}, ErrUIDMismatch
+			}
+			uid = d.StringValue
+		}
+	}
+	var (
+		remapper pidRemapper
+		entries  []*mergeEntry
+		byKey    = make(map[string]*mergeEntry)
+	)
+	for version, v := range []Vcard{base, a, b} {
+		for _, d := range remapper.remap(v) {
+			d := d
+			keys := mergeKeys(d)
+			var entry *mergeEntry
+			for _, k := range keys {
+				if e, ok := byKey[k]; ok && e.versions[version] == nil {
+					entry = e
+					break
+				}
+			}
+			if entry == nil {
+				entry = &mergeEntry{}
+				entries = append(entries, entry)
+			}
+			entry.versions[version] = &d
+			for _, k := range keys {
+				if _, ok := byKey[k]; !ok {
+					byKey[k] = entry
+				}
+			}
+		}
+	}
+	merged := Vcard{Version: b.Version}
+	if merged.Version == "" {
+		merged.Version = a.Version
+	}
+	for i, uri := range remapper.uris {
+		merged.Data = append(merged.Data, ClientPIDMap{Source: i + 1, URI: uri}.Datum())
+	}
+	for _, e := range entries {
+		if d, ok := e.resolve(); ok {
+			merged.Data = append(merged.Data, d)
+		}
+	}
+	return merged.SyncFields(), nil
+}
+
+// resolve picks the merged version of a property, if it survives the merge.
+func (e *mergeEntry) resolve() (VcardDatum, bool) {
+	base, a, b := e.versions[0], e.versions[1], e.versions[2]
+	changed := func(d *VcardDatum) bool {
+		return d != nil && (base == nil || !base.Equal(*d))
+	}
+	var chosen *VcardDatum
+	switch {
+	case base == nil:
+		chosen = b
+		if chosen == nil {
+			chosen = a
+		}
+	case changed(b):
+		chosen = b
+	case changed(a):
+		chosen = a
+	case a == nil || b == nil:
+		// Deleted on one side and untouched on the other.
+		return emptyDatum, false
+	default:
+		chosen = base
+	}
+	if chosen == nil {
+		return emptyDatum, false
+	}
+	result := *chosen
+	var pids []PID
+	for _, d := range []*VcardDatum{a, b} {
+		if d != nil {
+			pids = appendMissingPIDs(pids, d.PIDs())
+		}
+	}
+	if len(pids) > 0 {
+		result.Attrs = copyAttrs(result.Attrs)
+		result.SetPIDs(pids...)
+	}
+	return result, true
+}
+
+// mergeKeys are the identities a property is matched on in Merge: its name
+// if it may only appear once, or else one per PID, or its name and value if
+// it has none.
+func mergeKeys(d VcardDatum) (keys []string) {
+	name := strings.ToUpper(d.FieldName)
+	if spec, ok := DefaultRegistry.Lookup(name); ok && spec.Cardinality.singular() {
+		return []string{name}
+	}
+	for _, pid := range d.PIDs() {
+		keys = append(keys, name+";PID="+pid.String())
+	}
+	if len(keys) == 0 {
+		value := d
+		value.Attrs = nil
+		out, _ := value.Output(nil)
+		keys = append(keys, out)
+	}
+	return keys
+}
+
+func appendMissingPIDs(pids []PID, more []PID) []PID {
+	for _, pid := range more {
+		found := false
+		for _, p := range pids {
+			if p == pid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Slice(pids, func(i, j int) bool {
+		if pids[i].Local != pids[j].Local {
+			return pids[i].Local < pids[j].Local
+		}
+		return pids[i].Source < pids[j].Source
+	})
+	return pids
+}
+
+// copyAttrs copies an AttrMap so it can be changed without touching data
+// that share it.
+func copyAttrs(attrs AttrMap) AttrMap {
+	if attrs == nil {
+		return nil
+	}
+	c := make(AttrMap, len(attrs))
+	for k, values := range attrs {
+		c[k] = append([]string(nil), values...)
+	}
+	return c
+}
@@ -0,0 +1,95 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	mergeBase = Vcard{Data: []VcardDatum{
+		StringDatum("UID", nil, "urn:uuid:4fbe8971-0bc3-424c-9c26-36c3e1eff6b1"),
+		SemicolonStructuredDatum("CLIENTPIDMAP", nil, "1", "urn:uuid:53e374d9-337e-4727-8803-a1e9c14e0556"),
+		StringDatum("FN", AttrMap{"PID": []string{"1.1"}}, "J. Doe"),
+		StringDatum("EMAIL", AttrMap{"PID": []string{"1.1"}}, "jdoe@example.com"),
+		StringDatum("TEL", AttrMap{"PID": []string{"1.1"}}, "tel:+1-555-555-5555"),
+	}}
+
+	// The phone renames, and adds an email.
+	mergePhone = Vcard{Data: []VcardDatum{
+		StringDatum("UID", nil, "urn:uuid:4fbe8971-0bc3-424c-9c26-36c3e1eff6b1"),
+		SemicolonStructuredDatum("CLIENTPIDMAP", nil, "1", "urn:uuid:53e374d9-337e-4727-8803-a1e9c14e0556"),
+		StringDatum("FN", AttrMap{"PID": []string{"1.1"}}, "John Doe"),
+		StringDatum("EMAIL", AttrMap{"PID": []string{"1.1"}}, "jdoe@example.com"),
+		StringDatum("TEL", AttrMap{"PID": []string{"1.1"}}, "tel:+1-555-555-5555"),
+		StringDatum("EMAIL", nil, "john@example.org"),
+	}}
+
+	// The web client numbers the same source differently, deletes the
+	// phone number and adds the same new email.
+	mergeWeb = Vcard{Data: []VcardDatum{
+		StringDatum("UID", nil, "urn:uuid:4fbe8971-0bc3-424c-9c26-36c3e1eff6b1"),
+		SemicolonStructuredDatum("CLIENTPIDMAP", nil, "1", "urn:uuid:1f762d2b-03c4-4a83-9a03-75ff658a6eee"),
+		SemicolonStructuredDatum("CLIENTPIDMAP", nil, "2", "urn:uuid:53e374d9-337e-4727-8803-a1e9c14e0556"),
+		StringDatum("FN", AttrMap{"PID": []string{"1.2"}}, "J. Doe"),
+		StringDatum("EMAIL", AttrMap{"PID": []string{"1.2"}}, "jdoe@example.com"),
+		StringDatum("EMAIL", nil, "john@example.org"),
+		StringDatum("NOTE", AttrMap{"PID": []string{"1.1"}}, "Met at the conference"),
+	}}
+)
+
+func TestClientPIDMap(t *testing.T) {
+	m, err := ParseClientPIDMap(mergeWeb.Data[2])
+	assert.Nil(t, err)
+	assert.Equal(t, ClientPIDMap{2, "urn:uuid:53e374d9-337e-4727-8803-a1e9c14e0556"}, m)
+	assert.EqualValues(t, mergeWeb.Data[2], m.Datum())
+	_, err = ParseClientPIDMap(SemicolonStructuredDatum("CLIENTPIDMAP", nil, "x", "urn:uuid:1"))
+	assert.Equal(t, ErrBadClientPIDMap, err)
+}
+
+func TestMerge(t *testing.T) {
+	merged, err := Merge(mergeBase, mergePhone, mergeWeb)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []VcardDatum{
+		SemicolonStructuredDatum("CLIENTPIDMAP", nil, "1", "urn:uuid:53e374d9-337e-4727-8803-a1e9c14e0556"),
+		SemicolonStructuredDatum("CLIENTPIDMAP", nil, "2", "urn:uuid:1f762d2b-03c4-4a83-9a03-75ff658a6eee"),
+		StringDatum("UID", nil, "urn:uuid:4fbe8971-0bc3-424c-9c26-36c3e1eff6b1"),
+		StringDatum("FN", AttrMap{"PID": []string{"1.1"}}, "John Doe"),
+		StringDatum("EMAIL", AttrMap{"PID": []string{"1.1"}}, "jdoe@example.com"),
+		StringDatum("EMAIL", nil, "john@example.org"),
+		StringDatum("NOTE", AttrMap{"PID": []string{"1.2"}}, "Met at the conference"),
+	}, merged.Data)
+}
+
+func TestMergeSingular(t *testing.T) {
+	base := Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Jane Doe"),
+		SemicolonStructuredDatum("N", nil, "Doe", "Jane", "", "", ""),
+		StringDatum("BDAY", nil, "19800101"),
+	}}
+	a := Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Jane Doe"),
+		SemicolonStructuredDatum("N", nil, "Doe", "Janet", "", "", ""),
+		StringDatum("BDAY", nil, "19800102"),
+	}}
+	b := Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Jane Doe"),
+		SemicolonStructuredDatum("N", nil, "Doe-Smith", "Jane", "", "", ""),
+		StringDatum("BDAY", nil, "19810101"),
+	}}
+	merged, err := Merge(base, a, b)
+	assert.Nil(t, err)
+	assert.EqualValues(t, b.Data, merged.Data)
+	assert.Empty(t, DefaultRegistry.Validate(merged))
+
+	// A change on one side alone is taken.
+	merged, err = Merge(base, a, base)
+	assert.Nil(t, err)
+	assert.EqualValues(t, a.Data, merged.Data)
+}
+
+func TestMergeUIDMismatch(t *testing.T) {
+	other := Vcard{Data: []VcardDatum{StringDatum("UID", nil, "urn:uuid:other")}}
+	_, err := Merge(Vcard{}, mergePhone, other)
+	assert.Equal(t, ErrUIDMismatch, err)
+}
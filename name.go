@@ -0,0 +1,74 @@
+package vcardenc
+
+import (
+	"strings"
+)
+
+// Name is an N value by name rather than by position. Each field may hold
+// several values, like two given names. The first five are those of
+// RFC 6350; RFC 9554 added secondary surnames, as in Spanish, and
+// generations like "Jr." or "III", and an N only carries them if one is set.
+type Name struct {
+	FamilyNames          []string `json:"familyNames,omitempty"`
+	GivenNames           []string `json:"givenNames,omitempty"`
+	AdditionalNames      []string `json:"additionalNames,omitempty"`
+	Prefixes             []string `json:"prefixes,omitempty"`
+	Suffixes             []string `json:"suffixes,omitempty"`
+	SecondaryFamilyNames []string `json:"secondaryFamilyNames,omitempty"`
+	Generations          []string `json:"generations,omitempty"`
+}
+
+// rfc6350NComponents is how many N components RFC 6350 defines.
+const rfc6350NComponents = 5
+
+func (n *Name) fields() []*[]string {
+	return []*[]string{
+		&n.FamilyNames, &n.GivenNames, &n.AdditionalNames, &n.Prefixes, &n.Suffixes,
+		&n.SecondaryFamilyNames, &n.Generations,
+	}
+}
+
+// Name reads the components of an N datum, dropping empty values.
+func (datum VcardDatum) Name() (n Name) {
+	for i, field := range n.fields() {
+		*field = dropEmpty(datum.Component(i))
+	}
+	return n
+}
+
+// NameDatum makes an N datum for the name, with five components unless
+// either of the RFC 9554 ones is set.
+func NameDatum(n Name, attrs AttrMap) VcardDatum {
+	fields := n.fields()
+	count := rfc6350NComponents
+	for i := count; i < len(fields); i++ {
+		if len(*fields[i]) > 0 {
+			count = len(fields)
+			break
+		}
+	}
+	components := make([][]string, 0, count)
+	for _, field := range fields[:count] {
+		if len(*field) == 0 {
+			components = append(components, []string{""})
+			continue
+		}
+		components = append(components, append([]string(nil), *field...))
+	}
+	return StructuredDatum("N", attrs, components...)
+}
+
+// Formatted joins the name in Western order, as a fallback for FN: prefixes,
+// given, additional, family and secondary family names, then generations
+// and suffixes after a comma.
+func (n Name) Formatted() string {
+	var parts []string
+	for _, field := range [][]string{n.Prefixes, n.GivenNames, n.AdditionalNames, n.FamilyNames, n.SecondaryFamilyNames} {
+		parts = append(parts, field...)
+	}
+	formatted := strings.Join(parts, " ")
+	if after := append(append([]string(nil), n.Generations...), n.Suffixes...); len(after) > 0 {
+		formatted = joinNonEmpty(", ", formatted, strings.Join(after, ", "))
+	}
+	return formatted
+}
@@ -0,0 +1,40 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var nameFormatTCs = map[string]Name{
+	"Dr. John Q. Public, Esq.": {
+		FamilyNames: []string{"Public"}, GivenNames: []string{"John"},
+		AdditionalNames: []string{"Q."}, Prefixes: []string{"Dr."}, Suffixes: []string{"Esq."},
+	},
+	"Gabriel García Márquez": {
+		FamilyNames: []string{"García"}, GivenNames: []string{"Gabriel"},
+		SecondaryFamilyNames: []string{"Márquez"},
+	},
+	"Sammy Davis, Jr.": {
+		FamilyNames: []string{"Davis"}, GivenNames: []string{"Sammy"}, Generations: []string{"Jr."},
+	},
+}
+
+func TestNameFormatted(t *testing.T) {
+	for expected, n := range nameFormatTCs {
+		assert.Equal(t, expected, n.Formatted())
+	}
+}
+
+func TestNameDatum(t *testing.T) {
+	d, err := ParseDatumLine("N:Stevenson;John;Philip,Paul;Dr.;Jr.,M.D.,A.C.P.")
+	assert.Nil(t, err)
+	n := d.Name()
+	assert.Equal(t, []string{"Philip", "Paul"}, n.AdditionalNames)
+	assert.Nil(t, n.Generations)
+	assert.True(t, d.Equal(NameDatum(n, nil)))
+
+	out, err := NameDatum(nameFormatTCs["Sammy Davis, Jr."], nil).Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "N:Davis;Sammy;;;;;Jr.\n", out)
+}
@@ -0,0 +1,270 @@
+package vcardenc
+
+import (
+	"io"
+	"mime/quotedprintable"
+	"sort"
+	"strings"
+)
+
+// Normalize returns a canonical form of the card using DefaultRegistry, so
+// that semantically identical cards compare, hash and store identically.
+func Normalize(v Vcard) Vcard {
+	return DefaultRegistry.Normalize(v)
+}
+
+// Normalize returns a canonical form of the card: names are upper case,
+// set-like parameters and comma-structured values are sorted, structured
+// values have the number of components their spec declares (or no empty
+// trailing components if it doesn't), legacy vCard 2.1 and 3.0 forms are
+// upgraded to 4.0, exact duplicates are removed, and data are sorted by
+// name and then encoding. The card's Version becomes "4.0".
+func (r *Registry) Normalize(v Vcard) Vcard {
+	normalized := Vcard{Version: "4.0"}
+	opts := EncodeOptions{Registry: r}
+	for _, d := range v.SyncData().Data {
+		d = r.normalizeDatum(d)
+		duplicate := false
+		for _, e := range normalized.Data {
+			if duplicate = e.equalWith(d, opts); duplicate {
+				break
+			}
+		}
+		if !duplicate {
+			normalized.Data = append(normalized.Data, d)
+		}
+	}
+	keys := make([]string, len(normalized.Data))
+	for i, d := range normalized.Data {
+		keys[i], _ = d.OutputWith(opts)
+	}
+	sort.Sort(datumsByKey{normalized.Data, keys})
+	return normalized.SyncFields()
+}
+
+type datumsByKey struct {
+	data []VcardDatum
+	keys []string
+}
+
+func (s datumsByKey) Len() int { return len(s.data) }
+
+func (s datumsByKey) Less(i, j int) bool {
+	if s.data[i].FieldName != s.data[j].FieldName {
+		return s.data[i].FieldName < s.data[j].FieldName
+	}
+	return s.keys[i] < s.keys[j]
+}
+
+func (s datumsByKey) Swap(i, j int) {
+	s.data[i], s.data[j] = s.data[j], s.data[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func (r *Registry) normalizeDatum(d VcardDatum) VcardDatum {
+	d.FieldName = strings.ToUpper(d.FieldName)
+	d.Spelling = nil
+	d.StructuredValue = copyComponents(d.StructuredValue)
+	if len(d.Attrs) > 0 {
+		attrs := make(AttrMap, len(d.Attrs))
+		for key, values := range d.Attrs {
+			key = strings.ToUpper(key)
+			attrs[key] = append(attrs[key], values...)
+		}
+		d.Attrs = attrs
+	} else {
+		d.Attrs = nil
+	}
+	upgradeLegacyForms(&d)
+	if types := d.Types(); len(types) > 0 {
+		d.SetTypes(sortedUnique(types)...)
+	}
+	if pids := d.PIDs(); len(pids) > 0 {
+		d.SetPIDs(appendMissingPIDs(nil, pids)...)
+	}
+	if len(d.Attrs) == 0 {
+		d.Attrs = nil
+	}
+	switch d.ValueType {
+	case CommaStructuredValueType:
+		values := dropEmpty(flattenComponents(d.StructuredValue))
+		d.StructuredValue = [][]string{sortedUnique(values)}
+	case SemicolonStructuredValueType:
+		components := 0
+		if spec, ok := r.Lookup(d.FieldName); ok {
+			components = spec.Components
+		}
+		d.StructuredValue = fitComponents(d.StructuredValue, components)
+	}
+	return d
+}
+
+// upgradeLegacyForms rewrites vCard 2.1 and 3.0 idioms in their 4.0 form:
+// TYPE=pref becomes PREF=1, EMAIL's TYPE=internet is dropped,
+// quoted-printable values are decoded from their CHARSET, inline base64
+// media becomes a data: URI by way of Media, and GEO and TZ take their 4.0
+// forms. CHARSET is dropped once the value is known to be UTF-8; values in
+// other charsets than UTF-8, ISO-8859-1 and Windows-1252 are left alone.
+func upgradeLegacyForms(d *VcardDatum) {
+	if d.HasType("pref") {
+		if _, ok := d.Pref(); !ok {
+			d.SetPref(1)
+		}
+		d.SetTypes(removeFold(d.Attrs.Get("TYPE"), "pref")...)
+	}
+	if d.FieldName == "EMAIL" {
+		d.SetTypes(removeFold(d.Attrs.Get("TYPE"), "internet")...)
+	}
+	charset := d.Attrs.first("CHARSET")
+	if isUTF8Charset(charset) {
+		d.Attrs.Del("CHARSET")
+	}
+	convertGeoTZ(d, "4.0")
+	encoding := d.Attrs.first("ENCODING")
+	switch {
+	case strings.EqualFold(encoding, "QUOTED-PRINTABLE") && d.ValueType == StringValueType:
+		decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(d.StringValue)))
+		if err != nil {
+			break
+		}
+		if s, ok := decodeCharset(decoded, charset); ok {
+			d.StringValue = s
+			d.Attrs.Del("ENCODING")
+			d.Attrs.Del("CHARSET")
+		}
+	case isBase64Encoding(encoding) || d.ValueType == BinaryValueType:
+		if m, err := d.Media(); err == nil {
+			*d = MediaDatum(d.FieldName, m, d.Attrs, "4.0")
+		}
+	}
+}
+
+// isUTF8Charset reports whether text in charset is already UTF-8, as it is
+// if no charset is declared.
+func isUTF8Charset(charset string) bool {
+	switch strings.ToUpper(charset) {
+	case "", "UTF-8", "UTF8", "US-ASCII", "ASCII":
+		return true
+	}
+	return false
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252, which differ
+// from ISO-8859-1, to runes. Unassigned bytes keep their ISO-8859-1 rune.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// decodeCharset converts text in charset to UTF-8, reporting false if the
+// charset isn't one it knows.
+func decodeCharset(b []byte, charset string) (string, bool) {
+	charset = strings.ToUpper(charset)
+	switch {
+	case isUTF8Charset(charset):
+		return string(b), true
+	case charset == "ISO-8859-1" || charset == "LATIN1" || charset == "WINDOWS-1252" || charset == "CP1252":
+		windows := strings.HasSuffix(charset, "1252")
+		out := make([]rune, len(b))
+		for i, c := range b {
+			out[i] = rune(c)
+			if windows && c >= 0x80 && c <= 0x9F {
+				out[i] = windows1252[c-0x80]
+			}
+		}
+		return string(out), true
+	}
+	return "", false
+}
+
+// legacyMediaType turns a vCard 3.0 TYPE like "JPEG" on a PHOTO into a
+// media type like "image/jpeg".
+func legacyMediaType(fieldName, t string) string {
+	t = strings.ToLower(t)
+	if strings.ContainsRune(t, '/') {
+		return t
+	}
+	switch fieldName {
+	case "KEY":
+		switch t {
+		case "pgp":
+			return "application/pgp-keys"
+		case "x509":
+			return "application/pkix-cert"
+		}
+	case "PHOTO", "LOGO":
+		return "image/" + t
+	case "SOUND":
+		// MP3 has no media type of its own.
+		if t == "mp3" {
+			return "audio/mpeg"
+		}
+		return "audio/" + t
+	}
+	return "application/" + t
+}
+
+// fitComponents pads or trims components to n, dropping only empty ones,
+// or if n is zero strips empty trailing components. Empty values are
+// dropped from multi-valued components, and empty components are [""].
+func fitComponents(components [][]string, n int) [][]string {
+	for i, c := range components {
+		if c = dropEmpty(c); len(c) == 0 {
+			c = []string{""}
+		}
+		components[i] = c
+	}
+	for len(components) > n && isEmptyComponent(components[len(components)-1]) {
+		components = components[:len(components)-1]
+	}
+	for len(components) < n {
+		components = append(components, []string{""})
+	}
+	return components
+}
+
+func isEmptyComponent(c []string) bool {
+	return len(c) == 0 || (len(c) == 1 && c[0] == "")
+}
+
+func copyComponents(components [][]string) [][]string {
+	if components == nil {
+		return nil
+	}
+	c := make([][]string, len(components))
+	for i, values := range components {
+		c[i] = append([]string(nil), values...)
+	}
+	return c
+}
+
+func dropEmpty(values []string) (kept []string) {
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func removeFold(values []string, remove string) (kept []string) {
+	for _, v := range values {
+		if !strings.EqualFold(v, remove) {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// sortedUnique sorts values, dropping duplicates.
+func sortedUnique(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, v := range sorted {
+		if i == 0 || v != sorted[i-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,72 @@
+package vcardenc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	legacyCard = "BEGIN:VCARD\nVERSION:3.0\nfn:Forrest Gump\nN:Gump;Forrest\nEMAIL;TYPE=INTERNET,pref:forrestgump@example.com\nTEL;type=VOICE;TYPE=home:+14045551212\nCATEGORIES:shrimp,running,,shrimp\nNOTE;CHARSET=UTF-8;ENCODING=QUOTED-PRINTABLE:Life is like a box of choc=\nolates\nPHOTO;ENCODING=b;TYPE=GIF:R0lGODlh\nEMAIL;TYPE=INTERNET,pref:forrestgump@example.com\nEND:VCARD\n"
+	modernCard = "BEGIN:VCARD\nVERSION:4.0\nCATEGORIES:running,shrimp\nEMAIL;PREF=1:forrestgump@example.com\nFN:Forrest Gump\nN:Gump;Forrest;;;\nNOTE:Life is like a box of chocolates\nPHOTO:data:image/gif;base64,R0lGODlh\nTEL;TYPE=home,voice:+14045551212\nEND:VCARD"
+)
+
+func TestNormalize(t *testing.T) {
+	legacy, err := ParseVcard(legacyCard, nil)
+	assert.Nil(t, err)
+	normalized := Normalize(legacy)
+	assert.Equal(t, "4.0", normalized.Version)
+	encoded, err := normalized.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, modernCard, encoded)
+
+	modern, err := ParseVcard(modernCard, nil)
+	assert.Nil(t, err)
+	assert.EqualValues(t, normalized, Normalize(modern))
+	assert.EqualValues(t, normalized, Normalize(normalized))
+}
+
+func TestNormalizeOptionalComponents(t *testing.T) {
+	card, err := ParseVcard("BEGIN:VCARD\nVERSION:4.0\nGENDER:M;\nN:Gump;Forrest\nEND:VCARD", nil)
+	assert.Nil(t, err)
+	encoded, err := Normalize(card).Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nGENDER:M\nN:Gump;Forrest;;;\nEND:VCARD", encoded)
+}
+
+func TestNormalizeCharset(t *testing.T) {
+	card, err := ParseVcard("BEGIN:VCARD\nVERSION:2.1\n"+
+		"NOTE;CHARSET=ISO-8859-1;ENCODING=QUOTED-PRINTABLE:caf=E9\n"+
+		"TITLE;CHARSET=windows-1252;ENCODING=QUOTED-PRINTABLE:=93Boss=94\n"+
+		"ROLE;CHARSET=KOI8-R;ENCODING=QUOTED-PRINTABLE:=F7=C1=CE=D1\n"+
+		"END:VCARD", nil)
+	assert.Nil(t, err)
+	normalized := Normalize(card)
+	note, _ := normalized.Get("NOTE")
+	assert.Equal(t, "café", note.StringValue)
+	assert.Nil(t, note.Attrs)
+	title, _ := normalized.Get("TITLE")
+	assert.Equal(t, "“Boss”", title.StringValue)
+	// Charsets it doesn't know are left for the reader to decode.
+	role, _ := normalized.Get("ROLE")
+	assert.Equal(t, "=F7=C1=CE=D1", role.StringValue)
+	assert.Equal(t, "KOI8-R", role.Attrs.first("CHARSET"))
+	assert.Equal(t, "QUOTED-PRINTABLE", role.Attrs.first("ENCODING"))
+}
+
+func TestNormalizeRegistry(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	r.Register(PropertySpec{Name: "X-CODE", ValueType: StringValueType, Encoder: func(d VcardDatum) (string, error) {
+		return "X-CODE:" + strings.ToLower(d.StringValue) + "\n", nil
+	}})
+	card := Vcard{Data: []VcardDatum{StringDatum("X-CODE", nil, "ABC"), StringDatum("X-CODE", nil, "abc")}}
+	assert.Len(t, r.Normalize(card).Data, 1)
+	assert.Len(t, Normalize(card).Data, 2)
+}
+
+func TestFitComponents(t *testing.T) {
+	assert.Equal(t, [][]string{{"Gump"}, {""}, {""}}, fitComponents([][]string{{"Gump"}, {}}, 3))
+	assert.Equal(t, [][]string{{"ABC, Inc."}}, fitComponents([][]string{{"ABC, Inc."}, {""}, {"", ""}}, 0))
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, fitComponents([][]string{{"a"}, {"b"}, {""}}, 1))
+}
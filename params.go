@@ -0,0 +1,371 @@
+package vcardenc
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrBadPID is returned when a PID parameter value isn't of the form
+// digits, optionally followed by a dot and more digits.
+var ErrBadPID = errors.New("Malformed PID parameter value")
+
+// PID is a PID parameter value as in RFC 6350 section 5.5. Local identifies
+// the property within the card, and Source, if not zero, is the CLIENTPIDMAP
+// entry of the client that created it.
+type PID struct {
+	Local  int
+	Source int
+}
+
+// ParsePID parses a PID parameter value like "1" or "3.1".
+func ParsePID(s string) (PID, error) {
+	localS, sourceS := s, ""
+	if dot := strings.IndexRune(s, '.'); dot != -1 {
+		localS, sourceS = s[:dot], s[dot+1:]
+		if sourceS == "" {
+			return PID{}, ErrBadPID
+		}
+	}
+	var (
+		pid PID
+		err error
+	)
+	if pid.Local, err = strconv.Atoi(localS); err != nil || pid.Local < 0 {
+		return PID{}, ErrBadPID
+	}
+	if sourceS != "" {
+		if pid.Source, err = strconv.Atoi(sourceS); err != nil || pid.Source < 0 {
+			return PID{}, ErrBadPID
+		}
+	}
+	return pid, nil
+}
+
+// String formats the PID as it appears in a PID parameter.
+func (pid PID) String() string {
+	if pid.Source == 0 {
+		return strconv.Itoa(pid.Local)
+	}
+	return strconv.Itoa(pid.Local) + "." + strconv.Itoa(pid.Source)
+}
+
+// singleValuedParams are the parameters that take a single value, in which
+// commas are just commas. Any others, like TYPE, PID and SORT-AS, take
+// lists of values.
+var singleValuedParams = []string{
+	"ALTID", "AUTHOR", "AUTHOR-NAME", "CALSCALE", "CHARSET", "CREATED",
+	"DERIVED", "ENCODING", "GEO", "JSPTR", "LABEL", "LANGUAGE", "MEDIATYPE",
+	"PHONETIC", "PREF", "PROP-ID", "SCRIPT", "SERVICE-TYPE", "TZ", "USERNAME",
+	"VALUE",
+}
+
+// isListParam reports whether the named parameter takes a list of values.
+func isListParam(name string) bool {
+	return !stringSliceContains(singleValuedParams, strings.ToUpper(name))
+}
+
+// Set replaces the values of the parameter named key, ignoring case, and
+// stores them under the upper-case key. Setting no values deletes it.
+func (attrs AttrMap) Set(key string, values ...string) {
+	attrs.Del(key)
+	if len(values) > 0 {
+		attrs[strings.ToUpper(key)] = values
+	}
+}
+
+// Del deletes the parameter named key, ignoring case.
+func (attrs AttrMap) Del(key string) {
+	for k := range attrs {
+		if strings.EqualFold(k, key) {
+			delete(attrs, k)
+		}
+	}
+}
+
+// first returns the first value of a parameter, or "".
+func (attrs AttrMap) first(key string) string {
+	values := attrs.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// sortedKeys returns the parameter names in order.
+func (attrs AttrMap) sortedKeys() []string {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setParam sets a parameter on the datum, making its AttrMap if need be.
+func (datum *VcardDatum) setParam(key string, values ...string) {
+	if datum.Attrs == nil {
+		if len(values) == 0 {
+			return
+		}
+		datum.Attrs = make(AttrMap)
+	}
+	datum.Attrs.Set(key, values...)
+}
+
+// Types returns the TYPE parameter values in lower case.
+func (datum VcardDatum) Types() []string {
+	var types []string
+	for _, t := range datum.Attrs.Get("TYPE") {
+		types = append(types, strings.ToLower(t))
+	}
+	return types
+}
+
+// HasType reports whether t is among the TYPE parameter values, ignoring case.
+func (datum VcardDatum) HasType(t string) bool {
+	for _, dt := range datum.Attrs.Get("TYPE") {
+		if strings.EqualFold(dt, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTypes replaces the TYPE parameter values.
+func (datum *VcardDatum) SetTypes(types ...string) {
+	datum.setParam("TYPE", types...)
+}
+
+// AddType adds t to the TYPE parameter values unless it's already there.
+func (datum *VcardDatum) AddType(t string) {
+	if datum.HasType(t) {
+		return
+	}
+	datum.setParam("TYPE", append(datum.Attrs.Get("TYPE"), t)...)
+}
+
+// Pref returns the PREF parameter, which runs from 1 (most preferred) to 100.
+// ok is false if there's no PREF or it isn't a number in that range.
+func (datum VcardDatum) Pref() (pref int, ok bool) {
+	pref, err := strconv.Atoi(datum.Attrs.first("PREF"))
+	if err != nil || pref < 1 || pref > 100 {
+		return 0, false
+	}
+	return pref, true
+}
+
+// SetPref sets the PREF parameter, or removes it if pref is zero.
+func (datum *VcardDatum) SetPref(pref int) {
+	if pref == 0 {
+		datum.setParam("PREF")
+		return
+	}
+	datum.setParam("PREF", strconv.Itoa(pref))
+}
+
+// Language returns the LANGUAGE parameter, a BCP 47 tag, or "".
+func (datum VcardDatum) Language() string {
+	return datum.Attrs.first("LANGUAGE")
+}
+
+// SetLanguage sets the LANGUAGE parameter, or removes it if tag is "".
+func (datum *VcardDatum) SetLanguage(tag string) {
+	datum.setOptionalParam("LANGUAGE", tag)
+}
+
+// AltID returns the ALTID parameter, or "".
+func (datum VcardDatum) AltID() string {
+	return datum.Attrs.first("ALTID")
+}
+
+// SetAltID sets the ALTID parameter, or removes it if altID is "".
+func (datum *VcardDatum) SetAltID(altID string) {
+	datum.setOptionalParam("ALTID", altID)
+}
+
+// PIDs returns the parsed PID parameter values, skipping malformed ones.
+func (datum VcardDatum) PIDs() []PID {
+	var pids []PID
+	for _, v := range datum.Attrs.Get("PID") {
+		if pid, err := ParsePID(v); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// SetPIDs replaces the PID parameter values.
+func (datum *VcardDatum) SetPIDs(pids ...PID) {
+	values := make([]string, 0, len(pids))
+	for _, pid := range pids {
+		values = append(values, pid.String())
+	}
+	datum.setParam("PID", values...)
+}
+
+// MediaType returns the MEDIATYPE parameter, or "".
+func (datum VcardDatum) MediaType() string {
+	return datum.Attrs.first("MEDIATYPE")
+}
+
+// SetMediaType sets the MEDIATYPE parameter, or removes it if mediaType is "".
+func (datum *VcardDatum) SetMediaType(mediaType string) {
+	datum.setOptionalParam("MEDIATYPE", mediaType)
+}
+
+// CalScale returns the CALSCALE parameter, or "" (which means gregorian).
+func (datum VcardDatum) CalScale() string {
+	return datum.Attrs.first("CALSCALE")
+}
+
+// SetCalScale sets the CALSCALE parameter, or removes it if calScale is "".
+func (datum *VcardDatum) SetCalScale(calScale string) {
+	datum.setOptionalParam("CALSCALE", calScale)
+}
+
+// SortAs returns the SORT-AS parameter values.
+func (datum VcardDatum) SortAs() []string {
+	return datum.Attrs.Get("SORT-AS")
+}
+
+// SetSortAs replaces the SORT-AS parameter values.
+func (datum *VcardDatum) SetSortAs(sortAs ...string) {
+	datum.setParam("SORT-AS", sortAs...)
+}
+
+// GeoParam returns the GEO parameter of an ADR, a geo: URI, or "".
+func (datum VcardDatum) GeoParam() string {
+	return datum.Attrs.first("GEO")
+}
+
+// SetGeoParam sets the GEO parameter, or removes it if uri is "".
+func (datum *VcardDatum) SetGeoParam(uri string) {
+	datum.setOptionalParam("GEO", uri)
+}
+
+// TZParam returns the TZ parameter of an ADR, or "".
+func (datum VcardDatum) TZParam() string {
+	return datum.Attrs.first("TZ")
+}
+
+// SetTZParam sets the TZ parameter, or removes it if tz is "".
+func (datum *VcardDatum) SetTZParam(tz string) {
+	datum.setOptionalParam("TZ", tz)
+}
+
+// Author returns the AUTHOR parameter of RFC 9554, a URI for whoever
+// added the property, or "".
+func (datum VcardDatum) Author() string {
+	return datum.Attrs.first("AUTHOR")
+}
+
+// SetAuthor sets the AUTHOR parameter, or removes it if uri is "".
+func (datum *VcardDatum) SetAuthor(uri string) {
+	datum.setOptionalParam("AUTHOR", uri)
+}
+
+// AuthorName returns the AUTHOR-NAME parameter, or "".
+func (datum VcardDatum) AuthorName() string {
+	return datum.Attrs.first("AUTHOR-NAME")
+}
+
+// SetAuthorName sets the AUTHOR-NAME parameter, or removes it if name is "".
+func (datum *VcardDatum) SetAuthorName(name string) {
+	datum.setOptionalParam("AUTHOR-NAME", name)
+}
+
+// CreatedParam returns the CREATED parameter, when the property was added.
+// ok is false if there's none or it isn't a timestamp.
+func (datum VcardDatum) CreatedParam() (created time.Time, ok bool) {
+	created, err := ParseTimestamp(datum.Attrs.first("CREATED"))
+	return created, err == nil
+}
+
+// SetCreatedParam sets the CREATED parameter, or removes it if t is zero.
+func (datum *VcardDatum) SetCreatedParam(t time.Time) {
+	if t.IsZero() {
+		datum.setParam("CREATED")
+		return
+	}
+	datum.setParam("CREATED", FormatTimestamp(t))
+}
+
+// Derived reports whether the DERIVED parameter marks the property as
+// computed from others, which means it shouldn't be edited by hand.
+func (datum VcardDatum) Derived() bool {
+	return strings.EqualFold(datum.Attrs.first("DERIVED"), "true")
+}
+
+// SetDerived sets DERIVED=TRUE, or removes the parameter.
+func (datum *VcardDatum) SetDerived(derived bool) {
+	if derived {
+		datum.setParam("DERIVED", "TRUE")
+		return
+	}
+	datum.setParam("DERIVED")
+}
+
+// Phonetic returns the PHONETIC parameter, the phonetic system of an
+// alternative representation like "ipa" or "jyut", in lower case, or "".
+func (datum VcardDatum) Phonetic() string {
+	return strings.ToLower(datum.Attrs.first("PHONETIC"))
+}
+
+// SetPhonetic sets the PHONETIC parameter, or removes it if system is "".
+func (datum *VcardDatum) SetPhonetic(system string) {
+	datum.setOptionalParam("PHONETIC", system)
+}
+
+// PropID returns the PROP-ID parameter, or "".
+func (datum VcardDatum) PropID() string {
+	return datum.Attrs.first("PROP-ID")
+}
+
+// SetPropID sets the PROP-ID parameter, or removes it if id is "".
+func (datum *VcardDatum) SetPropID(id string) {
+	datum.setOptionalParam("PROP-ID", id)
+}
+
+// Script returns the SCRIPT parameter, an ISO 15924 code like "Latn", or "".
+func (datum VcardDatum) Script() string {
+	return datum.Attrs.first("SCRIPT")
+}
+
+// SetScript sets the SCRIPT parameter, or removes it if script is "".
+func (datum *VcardDatum) SetScript(script string) {
+	datum.setOptionalParam("SCRIPT", script)
+}
+
+// ServiceType returns the SERVICE-TYPE parameter of an IMPP or
+// SOCIALPROFILE, like "Mastodon", or "".
+func (datum VcardDatum) ServiceType() string {
+	return datum.Attrs.first("SERVICE-TYPE")
+}
+
+// SetServiceType sets the SERVICE-TYPE parameter, or removes it if
+// service is "".
+func (datum *VcardDatum) SetServiceType(service string) {
+	datum.setOptionalParam("SERVICE-TYPE", service)
+}
+
+// Username returns the USERNAME parameter of an IMPP or SOCIALPROFILE,
+// or "".
+func (datum VcardDatum) Username() string {
+	return datum.Attrs.first("USERNAME")
+}
+
+// SetUsername sets the USERNAME parameter, or removes it if username is "".
+func (datum *VcardDatum) SetUsername(username string) {
+	datum.setOptionalParam("USERNAME", username)
+}
+
+func (datum *VcardDatum) setOptionalParam(key, value string) {
+	if value == "" {
+		datum.setParam(key)
+		return
+	}
+	datum.setParam(key, value)
+}
@@ -0,0 +1,64 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	pidTCs = map[string]PID{
+		"1":    PID{1, 0},
+		"3.1":  PID{3, 1},
+		"12.4": PID{12, 4},
+	}
+	badPIDs = []string{"", "a", "1.", ".1", "1.b", "-1"}
+)
+
+func TestParsePID(t *testing.T) {
+	for s, expected := range pidTCs {
+		pid, err := ParsePID(s)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, pid)
+		assert.Equal(t, s, pid.String())
+	}
+	for _, s := range badPIDs {
+		_, err := ParsePID(s)
+		assert.Equal(t, ErrBadPID, err, s)
+	}
+}
+
+func TestTypedParams(t *testing.T) {
+	d, err := ParseDatumLine("TEL;type=WORK,voice;PREF=1;PID=1.1,2.1;ALTID=1;LANGUAGE=en:tel:+11115551212")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"work", "voice"}, d.Types())
+	assert.True(t, d.HasType("Work"))
+	assert.False(t, d.HasType("home"))
+	pref, ok := d.Pref()
+	assert.True(t, ok)
+	assert.Equal(t, 1, pref)
+	assert.Equal(t, []PID{{1, 1}, {2, 1}}, d.PIDs())
+	assert.Equal(t, "1", d.AltID())
+	assert.Equal(t, "en", d.Language())
+
+	d.AddType("cell")
+	d.AddType("CELL")
+	d.SetPref(0)
+	d.SetLanguage("")
+	d.SetPIDs(PID{Local: 4})
+	_, ok = d.Pref()
+	assert.False(t, ok)
+	assert.EqualValues(t, AttrMap{
+		"TYPE":  []string{"WORK", "voice", "cell"},
+		"PID":   []string{"4"},
+		"ALTID": []string{"1"},
+	}, d.Attrs)
+}
+
+func TestSettersOnBareDatum(t *testing.T) {
+	d := SemicolonStructuredDatum("N", nil, "Gump", "Forrest", "", "", "")
+	d.SetSortAs()
+	assert.Nil(t, d.Attrs)
+	d.SetSortAs("Gump", "Forrest")
+	assert.EqualValues(t, AttrMap{"SORT-AS": []string{"Gump", "Forrest"}}, d.Attrs)
+}
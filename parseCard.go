@@ -0,0 +1,177 @@
+package vcardenc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNoBeginVcard is returned when card data doesn't open with BEGIN:VCARD.
+	ErrNoBeginVcard = errors.New("Expected BEGIN:VCARD before card data")
+
+	// ErrUnterminatedVcard is returned when a card has no END:VCARD.
+	ErrUnterminatedVcard = errors.New("Card ended without END:VCARD")
+)
+
+// CardError ties a parsing error to the card it spoiled, counting from zero
+// in the order cards begin, and to the line it was found on, counting from
+// one. Card is -1 for stray lines outside any card.
+type CardError struct {
+	Card int
+	Line int
+	Err  error
+}
+
+func (e CardError) Error() string {
+	msg := "line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+	if e.Card < 0 {
+		return msg
+	}
+	return "card " + strconv.Itoa(e.Card) + ", " + msg
+}
+
+// Unwrap returns the underlying error, so errors.Is works on CardErrors.
+func (e CardError) Unwrap() error {
+	return e.Err
+}
+
+// ParseErrors are the errors of the cards ParseVcards skipped and of any
+// stray lines between cards, in order.
+type ParseErrors []CardError
+
+func (errs ParseErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// unwrapLines splits card data into logical lines, joining folded lines
+// (those starting with a space or tab) onto the line before and dropping
+// the single whitespace character that marks the fold. Blank lines are dropped.
+// Quoted-printable soft line breaks are joined too.
+func unwrapLines(data string) (lines []string) {
+	lines, _ = unwrapNumberedLines(data)
+	return lines
+}
+
+// unwrapNumberedLines is unwrapLines, also returning the physical line
+// number, counting from one, that each logical line starts on.
+func unwrapNumberedLines(data string) (lines []string, numbers []int) {
+	data = strings.Replace(data, "\r\n", "\n", -1)
+	for i, physical := range strings.Split(data, "\n") {
+		if len(lines) > 0 && (strings.HasPrefix(physical, " ") || strings.HasPrefix(physical, "\t")) {
+			lines[len(lines)-1] += physical[1:]
+			continue
+		}
+		// vCard 2.1 quoted-printable values fold with a trailing "=" instead.
+		if n := len(lines); n > 0 && strings.HasSuffix(lines[n-1], "=") && isQuotedPrintable(lines[n-1]) {
+			lines[n-1] = lines[n-1][:len(lines[n-1])-1] + physical
+			continue
+		}
+		if strings.TrimSpace(physical) == "" {
+			continue
+		}
+		lines = append(lines, physical)
+		numbers = append(numbers, i+1)
+	}
+	return lines, numbers
+}
+
+// isQuotedPrintable reports whether the parameters of an unparsed line
+// declare a quoted-printable value.
+func isQuotedPrintable(line string) bool {
+	colon := strings.IndexRune(line, ':')
+	if colon == -1 {
+		return false
+	}
+	return strings.Contains(strings.ToUpper(line[:colon]), "QUOTED-PRINTABLE")
+}
+
+// ParseVcard parses a single card. specialRules, if provided, overrides the
+// decoding of particular FieldNames just as for ParseDatumLineSpecial.
+func ParseVcard(data string, specialRules map[string]DatumDecoder) (Vcard, error) {
+	return DefaultRegistry.ParseVcard(data, specialRules)
+}
+
+// ParseVcards parses every card in data, as exported address books
+// usually hold several. A card that fails to parse is skipped rather than
+// losing the rest: the cards that parsed are returned along with
+// ParseErrors saying which were skipped and why. Stray lines between
+// cards are reported by line number, and a leading byte order mark is
+// ignored.
+func ParseVcards(data string, specialRules map[string]DatumDecoder) ([]Vcard, error) {
+	return DefaultRegistry.ParseVcards(data, specialRules)
+}
+
+// ParseVcard is ParseVcard, using the specs of this registry.
+func (r *Registry) ParseVcard(data string, specialRules map[string]DatumDecoder) (Vcard, error) {
+	cards, errs := r.parseVcards(data, specialRules)
+	for _, err := range errs {
+		if err.Card == 0 {
+			return Vcard{}, err.Err
+		}
+	}
+	if len(cards) == 0 {
+		return Vcard{}, ErrNoBeginVcard
+	}
+	return cards[0], nil
+}
+
+// ParseVcards is ParseVcards, using the specs of this registry.
+func (r *Registry) ParseVcards(data string, specialRules map[string]DatumDecoder) ([]Vcard, error) {
+	cards, errs := r.parseVcards(data, specialRules)
+	if len(errs) > 0 {
+		return cards, errs
+	}
+	return cards, nil
+}
+
+func (r *Registry) parseVcards(data string, specialRules map[string]DatumDecoder) (cards []Vcard, errs ParseErrors) {
+	var (
+		card    Vcard
+		inCard  bool
+		skip    bool // Skipping the rest of a bad card, or stray lines.
+		started int  // How many cards have begun.
+		begun   int  // The line the current card began on.
+	)
+	fail := func(i, line int, err error) {
+		errs = append(errs, CardError{i, line, err})
+		inCard, skip = false, true
+	}
+	lines, numbers := unwrapNumberedLines(strings.TrimPrefix(data, "\uFEFF"))
+	for n, line := range lines {
+		datum, err := r.ParseDatumLineSpecial(line, specialRules)
+		field := strings.ToUpper(datum.FieldName)
+		switch {
+		case err == nil && field == "BEGIN" && strings.EqualFold(datum.StringValue, "VCARD"):
+			if inCard {
+				fail(started-1, begun, ErrUnterminatedVcard)
+			}
+			card, inCard, skip = Vcard{}, true, false
+			started++
+			begun = numbers[n]
+		case skip:
+			if err == nil && field == "END" && strings.EqualFold(datum.StringValue, "VCARD") {
+				skip = false
+			}
+		case !inCard:
+			// Only the first of a run of stray lines is reported.
+			fail(-1, numbers[n], ErrNoBeginVcard)
+		case err != nil:
+			fail(started-1, numbers[n], err)
+		case field == "END" && strings.EqualFold(datum.StringValue, "VCARD"):
+			cards, inCard = append(cards, card.SyncFields()), false
+		case field == "VERSION":
+			card.Version = datum.StringValue
+		default:
+			card.Data = append(card.Data, datum)
+		}
+	}
+	if inCard {
+		fail(started-1, begun, ErrUnterminatedVcard)
+	}
+	return cards, errs
+}
@@ -0,0 +1,68 @@
+package vcardenc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	socialProfileCard = "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Forrest Gump\r\nNOTE:Life is like a box of choc\r\n olates.\r\nX-SOCIALPROFILE;TYPE=twitter:https://twitter.com/fgump\r\nEND:VCARD\r\n"
+)
+
+func TestUnwrapLines(t *testing.T) {
+	lines := unwrapLines(wikipediaCard)
+	assert.Len(t, lines, 14)
+	assert.True(t, strings.Contains(lines[9], `United States of America":`), lines[9])
+	assert.True(t, strings.Contains(lines[10], `United States of America":`), lines[10])
+}
+
+func TestParseVcardSpecialRules(t *testing.T) {
+	decodeProfile := func(fieldName string, attrs AttrMap, rawValue string) (VcardDatum, error) {
+		return StringDatum(fieldName, AttrMap{"X-SERVICE": attrs["TYPE"]}, rawValue), nil
+	}
+	card, err := ParseVcard(socialProfileCard, map[string]DatumDecoder{"X-SOCIALPROFILE": decodeProfile})
+	assert.Nil(t, err)
+	assert.Equal(t, "3.0", card.Version)
+	assert.EqualValues(t, []VcardDatum{
+		StringDatum("FN", nil, "Forrest Gump"),
+		StringDatum("NOTE", nil, "Life is like a box of chocolates."),
+		StringDatum("X-SOCIALPROFILE", AttrMap{"X-SERVICE": []string{"twitter"}}, "https://twitter.com/fgump"),
+	}, card.Data)
+}
+
+func TestParseVcards(t *testing.T) {
+	cards, err := ParseVcards(socialProfileCard+socialProfileCard, nil)
+	assert.Nil(t, err)
+	assert.Len(t, cards, 2)
+
+	_, err = ParseVcard("FN:Forrest Gump\n", nil)
+	assert.Equal(t, ErrNoBeginVcard, err)
+	_, err = ParseVcard("BEGIN:VCARD\nFN:Forrest Gump\n", nil)
+	assert.Equal(t, ErrUnterminatedVcard, err)
+
+	// Bad cards are skipped, and the rest kept. Stray lines are reported
+	// but don't spoil the card after them.
+	bad := "BEGIN:VCARD\nVERSION:4.0\nno colon here\nFN:Lost\nEND:VCARD\n"
+	cards, err = ParseVcards(socialProfileCard+bad+"FN:Stray\nFN:Stray\n"+socialProfileCard+"BEGIN:VCARD\nFN:Unfinished\n", nil)
+	assert.Len(t, cards, 2)
+	errs, ok := err.(ParseErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 3)
+	assert.Equal(t, CardError{-1, 13, ErrNoBeginVcard}, errs[1])
+	assert.Equal(t, CardError{3, 22, ErrUnterminatedVcard}, errs[2])
+	assert.Equal(t, 1, errs[0].Card)
+	assert.Equal(t, 10, errs[0].Line)
+	assert.Error(t, errs[0].Err)
+	assert.Equal(t, "line 13: Expected BEGIN:VCARD before card data", errs[1].Error())
+	assert.Equal(t, "card 3, line 22: Card ended without END:VCARD", errs[2].Error())
+
+	// A byte order mark or a junk line before the first card loses nothing.
+	card, err := ParseVcard("\uFEFF"+socialProfileCard, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Forrest Gump", card.Data[0].StringValue)
+	card, err = ParseVcard("junk\n"+socialProfileCard, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Forrest Gump", card.Data[0].StringValue)
+}
@@ -32,13 +32,90 @@ var (
 )
 
 // ParseDatumLine accepts a pre-unwrapped line of data and parses it into
-// three chunks; name, attr, value. These are then decoded to a VcardDatum.
+// three chunks; name, attr, value. These are then decoded to a VcardDatum
+// according to DefaultRegistry.
 func ParseDatumLine(line string) (parsed VcardDatum, err error) {
-	fn, attrMap, val, err := splitDatumLine(line)
+	return DefaultRegistry.ParseDatumLine(line)
+}
+
+// ParseDatumLineSpecial is ParseDatumLine, but specialRules, if provided, is
+// a map of FieldNames to decoding functions that override the default
+// behaviour, mirroring the specialRules of VcardDatum.Output.
+func ParseDatumLineSpecial(line string, specialRules map[string]DatumDecoder) (parsed VcardDatum, err error) {
+	return DefaultRegistry.ParseDatumLineSpecial(line, specialRules)
+}
+
+// ParseDatumLine is ParseDatumLine, but uses the specs of this registry
+// to decide how each property is decoded.
+func (r *Registry) ParseDatumLine(line string) (parsed VcardDatum, err error) {
+	return r.ParseDatumLineSpecial(line, nil)
+}
+
+// ParseDatumLineSpecial is ParseDatumLineSpecial, but uses the specs of this
+// registry for anything specialRules doesn't cover.
+func (r *Registry) ParseDatumLineSpecial(line string, specialRules map[string]DatumDecoder) (parsed VcardDatum, err error) {
+	fn, params, val, err := splitDatumLine(line)
 	if err != nil {
 		return emptyDatum, err
 	}
-	vt := guessValueType(fn, attrMap, val)
+	fn, attrMap, spelling := canonicaliseNames(fn, params)
+	if specialFunc, ok := lookupRule(specialRules, fn); ok {
+		parsed, err = specialFunc(fn, attrMap, val)
+	} else if spec, ok := r.Lookup(fn); ok && spec.Decoder != nil {
+		parsed, err = spec.Decoder(fn, attrMap, val)
+	} else {
+		parsed, err = decodeDatum(fn, attrMap, val, r.guessValueType(fn, attrMap, val), r.isURIValued(fn, attrMap))
+	}
+	if err != nil {
+		return emptyDatum, err
+	}
+	if parsed.Spelling == nil {
+		parsed.Spelling = spelling
+	}
+	return parsed, nil
+}
+
+// canonicaliseNames upper-cases the field name and parameter names of a
+// parsed line, merging parameters that differ only in case in the order
+// they were written, and returns a Spelling of the originals if any of
+// them weren't upper case already.
+func canonicaliseNames(fn string, params []rawParam) (string, AttrMap, *Spelling) {
+	var spelling Spelling
+	canonicalFn := strings.ToUpper(fn)
+	if canonicalFn != fn {
+		spelling.FieldName = fn
+	}
+	var canonicalAttrs AttrMap
+	if params != nil {
+		canonicalAttrs = make(AttrMap, len(params))
+	}
+	spelt := make(map[string][]string)
+	respelt := make(map[string]bool)
+	for _, p := range params {
+		canonicalKey := strings.ToUpper(p.Name)
+		if canonicalKey != p.Name {
+			respelt[canonicalKey] = true
+		}
+		canonicalAttrs[canonicalKey] = append(canonicalAttrs[canonicalKey], p.Values...)
+		for range p.Values {
+			spelt[canonicalKey] = append(spelt[canonicalKey], p.Name)
+		}
+	}
+	for key := range respelt {
+		if spelling.Attrs == nil {
+			spelling.Attrs = make(map[string][]string)
+		}
+		spelling.Attrs[key] = spelt[key]
+	}
+	if spelling.FieldName == "" && spelling.Attrs == nil {
+		return canonicalFn, canonicalAttrs, nil
+	}
+	return canonicalFn, canonicalAttrs, &spelling
+}
+
+// decodeDatum decodes a raw value as the given valueType. String values
+// are unescaped unless they are URIs.
+func decodeDatum(fn string, attrMap AttrMap, val string, vt valueType, uri bool) (VcardDatum, error) {
 	finishedDatum := VcardDatum{
 		FieldName: fn,
 		Attrs:     attrMap,
@@ -48,6 +125,9 @@ func ParseDatumLine(line string) (parsed VcardDatum, err error) {
 	case StringValueType:
 		{
 			finishedDatum.StringValue = val
+			if !uri {
+				finishedDatum.StringValue = unescape(val)
+			}
 		}
 	case CommaStructuredValueType:
 		{
@@ -55,11 +135,11 @@ func ParseDatumLine(line string) (parsed VcardDatum, err error) {
 			if err != nil {
 				return emptyDatum, err
 			}
-			finishedDatum.StructuredValue = sval
+			finishedDatum.StructuredValue = [][]string{sval}
 		}
 	case SemicolonStructuredValueType:
 		{
-			sval, err := parseStructuredValue(val, ';')
+			sval, err := parseComponents(val)
 			if err != nil {
 				return emptyDatum, err
 			}
@@ -67,51 +147,52 @@ func ParseDatumLine(line string) (parsed VcardDatum, err error) {
 		}
 	case BinaryValueType:
 		{
-			dval, err := base64.StdEncoding.DecodeString(val)
+			dval, err := base64.StdEncoding.DecodeString(stripSpace(val))
 			if err != nil {
 				return emptyDatum, err
 			}
 			finishedDatum.BinaryValue = dval
 		}
 	default:
-		panic("Unexpected valueType returned from guessValueType (?)")
+		return emptyDatum, ErrBadDatumType
 	}
 	return finishedDatum, nil
 }
 
 // Parse a comma-or-semicolon seperated value string of escaped shit into a slice.
 func parseStructuredValue(valS string, delimiter rune) (sval []string, err error) {
-	var (
-		parsedVal string
-	)
-	delimString := string([]rune{delimiter})
-	for {
-		parsedVal, valS, err = parseQuotedValue(valS, []rune{delimiter}, false)
+	valS = strings.TrimSuffix(valS, "\n")
+	for _, raw := range splitUnescaped(valS, delimiter) {
+		sval = append(sval, unescape(raw))
+	}
+	return sval, nil
+}
+
+// Parse a semicolon-delimited list of components, each of which is a
+// comma-delimited list of values, as in ADR or N.
+func parseComponents(valS string) (components [][]string, err error) {
+	valS = strings.TrimSuffix(valS, "\n")
+	for _, rawComponent := range splitUnescaped(valS, ';') {
+		values, err := parseStructuredValue(rawComponent, ',')
 		if err != nil {
 			return nil, err
 		}
-		if len(valS) == 0 || valS == "\n" {
-			break
-		}
-		sval = append(sval, parsedVal)
-		if valS[0:1] == delimString {
-			valS = valS[1:]
-		}
+		components = append(components, values)
 	}
-	return sval, nil
+	return components, nil
 }
 
 // Parses an un-wrapped line to the three key portions of a vCard datum.
-func splitDatumLine(line string) (fieldName string, attrs AttrMap, value string, err error) {
+func splitDatumLine(line string) (fieldName string, params []rawParam, value string, err error) {
 	fieldName, line, err = parseFieldName(line)
 	if err != nil {
 		return "", nil, "", err
 	}
-	attrs, value, err = parseAttrs(line)
+	params, value, err = parseParams(line)
 	if err != nil {
 		return "", nil, "", err
 	}
-	return fieldName, attrs, value, nil
+	return fieldName, params, value, nil
 }
 
 func parseFieldName(line string) (fieldName, remainder string, err error) {
@@ -129,9 +210,16 @@ func parseFieldName(line string) (fieldName, remainder string, err error) {
 	return fieldName, remainder, nil
 }
 
+// rawParam is a parameter as written on a line, before names are
+// canonicalised and repeated parameters merged.
+type rawParam struct {
+	Name   string
+	Values []string
+}
+
 // parses key=<value>;key=<value>:datumValue where <value> may be escaped
-// or quoted.
-func parseAttrs(line string) (attrs AttrMap, value string, err error) {
+// or quoted, returning the parameters in the order written.
+func parseParams(line string) (params []rawParam, value string, err error) {
 	// First deal with case where there's no attrs at all:
 	if line[:1] == ":" {
 		return nil, line[1:], nil
@@ -141,43 +229,49 @@ func parseAttrs(line string) (attrs AttrMap, value string, err error) {
 		return nil, "", ErrNoMetadataFound
 	}
 	line = line[1:]
-	attrs = make(AttrMap)
+	params = []rawParam{}
 	for {
-		var rawMetaValue string
 		nextDelimiter := strings.IndexRune(line, '=')
-		if nextDelimiter == -1 && line[:1] != ":" {
+		if nextDelimiter == -1 {
 			return nil, "", ErrBadMetadata
 		}
-		metaFieldName := line[:nextDelimiter]
+		p := rawParam{Name: line[:nextDelimiter], Values: []string{}}
 		line = line[nextDelimiter+1:]
-		if line[:1] == "\"" {
-			rawMetaValue, line, err = parseQuotedValue(line[1:], []rune{'"'}, true)
-		} else {
-			rawMetaValue, line, err = parseQuotedValue(line, []rune{';', ':'}, false)
-		}
-		if err != nil {
-			return nil, "", err
-		}
-		if len(rawMetaValue) == 0 {
-			return nil, "", ErrBadMetadata
-		}
-		attrs[metaFieldName] = []string{} // []string{metaValue}
+		// Values are separated by commas, and each may be quoted, so that
+		// it can hold commas, semicolons and colons itself.
+		var items []string
 		for {
-			var nextVal string
-			if len(rawMetaValue) == 0 {
-				break
+			var item string
+			if strings.HasPrefix(line, "\"") {
+				item, line, err = parseQuotedValue(line[1:], []rune{'"'}, true)
+			} else {
+				item, line, err = parseQuotedValue(line, []rune{',', ';', ':'}, false)
 			}
-			nextVal, rawMetaValue, err = parseQuotedValue(rawMetaValue, []rune{',', ':'}, false)
 			if err != nil {
-				return nil, "", ErrBadMetadata
+				return nil, "", err
 			}
-			if len(nextVal) == 0 {
+			items = append(items, item)
+			if !strings.HasPrefix(line, ",") {
 				break
 			}
-			if len(rawMetaValue) > 0 && rawMetaValue[:1] == "," {
-				rawMetaValue = rawMetaValue[1:]
+			line = line[1:]
+		}
+		if isListParam(p.Name) {
+			// Quoted lists like TYPE="work,voice" are common enough.
+			for _, value := range strings.Split(strings.Join(items, ","), ",") {
+				if value != "" {
+					p.Values = append(p.Values, value)
+				}
 			}
-			attrs[metaFieldName] = append(attrs[metaFieldName], nextVal)
+		} else if value := strings.Join(items, ","); value != "" {
+			p.Values = append(p.Values, value)
+		}
+		if len(p.Values) == 0 {
+			return nil, "", ErrBadMetadata
+		}
+		params = append(params, p)
+		if line == "" {
+			return nil, "", ErrBadMetadata
 		}
 		if line[:1] == ":" {
 			line = line[1:]
@@ -187,6 +281,7 @@ func parseAttrs(line string) (attrs AttrMap, value string, err error) {
 			line = line[1:]
 			continue
 		}
+		return nil, "", ErrBadMetadata
 	}
-	return attrs, line, nil
+	return params, line, nil
 }
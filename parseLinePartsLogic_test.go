@@ -90,23 +90,23 @@ func TestCommaStructuredValueParsing(t *testing.T) {
 }
 
 type expectedMetaParseValues struct {
-	Attrs     AttrMap
+	Params    []rawParam
 	Remaining string
 }
 
 var (
 	metaParseTCs = map[string]expectedMetaParseValues{
 		";foo=bar;baz=qux,qum:some value we don't care about": expectedMetaParseValues{
-			AttrMap{"foo": []string{"bar"}, "baz": []string{"qux", "qum"}}, "some value we don't care about",
+			[]rawParam{{"foo", []string{"bar"}}, {"baz", []string{"qux", "qum"}}}, "some value we don't care about",
 		},
 	}
 )
 
 func TestMetaDataParse(t *testing.T) {
 	for metaEGstring, parsedTC := range metaParseTCs {
-		attrParsed, remainderParsed, err := parseAttrs(metaEGstring)
+		paramsParsed, remainderParsed, err := parseParams(metaEGstring)
 		assert.Nil(t, err)
-		assert.EqualValues(t, parsedTC.Attrs, attrParsed)
+		assert.EqualValues(t, parsedTC.Params, paramsParsed)
 		assert.EqualValues(t, parsedTC.Remaining, remainderParsed)
 	}
 }
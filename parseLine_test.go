@@ -11,9 +11,11 @@ var (
 		"FN:Forrest Gump": StringDatum("FN", nil, "Forrest Gump"),
 		"PHOTO;MEDIATYPE=image/gif:http://www.example.com/dir_photos/my_photo.gif": StringDatum("PHOTO", map[string][]string{"MEDIATYPE": []string{"image/gif"}}, "http://www.example.com/dir_photos/my_photo.gif"),
 		"TEL;VALUE=uri;TYPE=home,voice:tel:+14045551212":                           StringDatum("TEL", map[string][]string{"TYPE": []string{"home", "voice"}, "VALUE": []string{"uri"}}, "tel:+14045551212"),
-		// Currently fails because quoted values are still broken into lists on commas,
-		// so metadata parsing needs an overhaul.
-		"ADR;TYPE=work;LABEL=\"100 Waters Edge\\nBaytown, LA 30314\\nUnited States of America\":;;100 Waters Edge;Baytown;LA;30314;United States of America": SemicolonStructuredDatum("ADR", map[string][]string{"TYPE": []string{"work"}, "LABEL": []string{"\"100 Waters Edge\nBaytown, LA 30314\nUnited States of America\""}}, "", "", "100 Waters Edge", "Baytown", "LA", "30314", "United States of America"),
+		"N:Public;John;Quinlan,Adams;Mr.;Esq.":                                     StructuredDatum("N", nil, []string{"Public"}, []string{"John"}, []string{"Quinlan", "Adams"}, []string{"Mr."}, []string{"Esq."}),
+		"ADR:;;Flat 3\\, 12 Main St;Dublin;;;Ireland":                              SemicolonStructuredDatum("ADR", nil, "", "", "Flat 3, 12 Main St", "Dublin", "", "", "Ireland"),
+		"NICKNAME:Jim,Jimmie\\, Jr.":                                               CommaStructuredDatum("NICKNAME", nil, "Jim", "Jimmie, Jr."),
+		"ORG:ABC\\, Inc.;North American Division;Marketing":                        SemicolonStructuredDatum("ORG", nil, "ABC, Inc.", "North American Division", "Marketing"),
+		"ADR;TYPE=work;LABEL=\"100 Waters Edge\\nBaytown, LA 30314\\nUnited States of America\":;;100 Waters Edge;Baytown;LA;30314;United States of America": SemicolonStructuredDatum("ADR", map[string][]string{"TYPE": []string{"work"}, "LABEL": []string{"100 Waters Edge\nBaytown, LA 30314\nUnited States of America"}}, "", "", "100 Waters Edge", "Baytown", "LA", "30314", "United States of America"),
 	}
 )
 
@@ -22,5 +24,101 @@ func TestParseDatumLine(t *testing.T) {
 		parsed, err := ParseDatumLine(line)
 		assert.Nil(t, err)
 		assert.EqualValues(t, expected, parsed)
+		out, err := expected.Output(nil)
+		assert.Nil(t, err)
+		reparsed, err := ParseDatumLine(unwrapLines(out)[0])
+		assert.Nil(t, err)
+		assert.EqualValues(t, expected, reparsed, out)
+	}
+}
+
+func TestParseDatumLineCase(t *testing.T) {
+	line := "tel;type=HOME;Type=voice;VALUE=uri:tel:+14045551212"
+	parsed, err := ParseDatumLine(line)
+	assert.Nil(t, err)
+	assert.Equal(t, "TEL", parsed.FieldName)
+	assert.EqualValues(t, AttrMap{"TYPE": []string{"HOME", "voice"}, "VALUE": []string{"uri"}}, parsed.Attrs)
+	assert.Equal(t, []string{"uri"}, parsed.Attrs.Get("value"))
+
+	out, err := parsed.Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "TEL;VALUE=uri;TYPE=HOME,voice:tel:+14045551212\n", out)
+	out, err = parsed.OutputWith(EncodeOptions{PreserveCase: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "tel;VALUE=uri;type=HOME;Type=voice:tel:+14045551212\n", out)
+
+	// Values added since parsing take the first spelling.
+	parsed.Attrs["TYPE"] = append(parsed.Attrs["TYPE"], "cell")
+	out, err = parsed.OutputWith(EncodeOptions{PreserveCase: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "tel;VALUE=uri;type=HOME,voice,cell:tel:+14045551212\n", out)
+
+	custom := func(fieldName string, attrs AttrMap, rawValue string) (VcardDatum, error) {
+		return StringDatum(fieldName, nil, "custom"), nil
 	}
+	parsed, err = ParseDatumLineSpecial("x-custom:value", map[string]DatumDecoder{"X-Custom": custom})
+	assert.Nil(t, err)
+	assert.Equal(t, "custom", parsed.StringValue)
+}
+
+func TestParseDatumLineEscaping(t *testing.T) {
+	parsed, err := ParseDatumLine(`NOTE:Line one\nLine two\; with a \\ backslash`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Line one\nLine two; with a \\ backslash", parsed.StringValue)
+	out, err := parsed.Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `NOTE:Line one\nLine two\; with a \\ backslash`+"\n", out)
+
+	// URIs are taken and written as they are.
+	parsed, err = ParseDatumLine(`URL:http://example.com/a\b;c`)
+	assert.Nil(t, err)
+	assert.Equal(t, `http://example.com/a\b;c`, parsed.StringValue)
+	out, err = parsed.Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `URL:http://example.com/a\b;c`+"\n", out)
+
+	// Unless VALUE says otherwise.
+	parsed, err = ParseDatumLine(`KEY;VALUE=text:a\;b`)
+	assert.Nil(t, err)
+	assert.Equal(t, "a;b", parsed.StringValue)
+
+	// Custom registries are heeded both ways.
+	r := DefaultRegistry.Clone()
+	r.Register(PropertySpec{Name: "X-HOMEPAGE", ValueType: StringValueType, URI: true})
+	parsed, err = r.ParseDatumLine(`X-HOMEPAGE:http://example.com/a;b`)
+	assert.Nil(t, err)
+	assert.Equal(t, `http://example.com/a;b`, parsed.StringValue)
+	out, err = parsed.OutputWith(EncodeOptions{Registry: r})
+	assert.Nil(t, err)
+	assert.Equal(t, `X-HOMEPAGE:http://example.com/a;b`+"\n", out)
+	out, err = parsed.Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `X-HOMEPAGE:http://example.com/a\;b`+"\n", out)
+}
+
+func TestParseQuotedParams(t *testing.T) {
+	parsed, err := ParseDatumLine(`TEL;TYPE="voice,home";PREF=1:tel:+14045551212`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"voice", "home"}, parsed.Attrs.Get("TYPE"))
+	assert.True(t, parsed.HasType("voice"))
+
+	parsed, err = ParseDatumLine(`TEL;TYPE="voice","home":tel:+14045551212`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"voice", "home"}, parsed.Attrs.Get("TYPE"))
+
+	parsed, err = ParseDatumLine(`ADR;GEO="geo:12.3457,78.910";TZ="-05:00";LABEL=Flat 3,12 Main St:;;;;;;`)
+	assert.Nil(t, err)
+	assert.Equal(t, "geo:12.3457,78.910", parsed.GeoParam())
+	assert.Equal(t, "-05:00", parsed.TZParam())
+	assert.Equal(t, "Flat 3,12 Main St", parsed.Label())
+	g, err := parsed.Geo()
+	assert.Nil(t, err)
+	assert.Equal(t, 12.3457, g.Latitude)
+
+	out, err := parsed.Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, `ADR;TZ="-05:00";LABEL="Flat 3,12 Main St";GEO="geo:12.3457,78.910":;;;;;;`+"\n", out)
+
+	_, err = ParseDatumLine(`TEL;TYPE="voice"home:tel:+14045551212`)
+	assert.Equal(t, ErrBadMetadata, err)
 }
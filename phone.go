@@ -0,0 +1,206 @@
+package vcardenc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrBadPhoneNumber is returned when a TEL value can't be read as a
+	// phone number at all.
+	ErrBadPhoneNumber = errors.New("Malformed phone number")
+
+	// ErrUnknownRegion is returned when a national phone number is parsed
+	// without a known default region to place it in.
+	ErrUnknownRegion = errors.New("Unknown region for national phone number")
+
+	// ErrUnknownCallingCode is returned when an international phone number
+	// doesn't start with any known country calling code.
+	ErrUnknownCallingCode = errors.New("Unknown country calling code")
+)
+
+// maxE164Digits is the most digits an E.164 number may have, and
+// minNationalDigits the fewest this will accept after the calling code.
+const (
+	maxE164Digits     = 15
+	minNationalDigits = 4
+)
+
+// Phone is a phone number normalized to E.164 parts.
+type Phone struct {
+	// CountryCode is the country calling code, like 1 or 44.
+	CountryCode int
+	// National is the national significant number, in digits.
+	National string
+	// Extension is the extension, in digits, or "".
+	Extension string
+}
+
+// ParsePhone reads a TEL value, either a tel: URI as in RFC 3966 or the
+// free text that 3.0 cards and people write: "+44 20 7946 0958",
+// "(404) 555-1212 x123", "00 353 1 234 5678". Numbers without an
+// international prefix are placed in defaultRegion, an ISO 3166 code like
+// "US", after dropping any trunk prefix.
+func ParsePhone(value, defaultRegion string) (Phone, error) {
+	var number, extension, context string
+	value = strings.TrimSpace(value)
+	if len(value) > 4 && strings.EqualFold(value[:4], "tel:") {
+		params := strings.Split(value[4:], ";")
+		number = params[0]
+		for _, param := range params[1:] {
+			kv := strings.SplitN(param, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			switch strings.ToLower(kv[0]) {
+			case "ext":
+				extension = kv[1]
+			case "phone-context":
+				context = kv[1]
+			}
+		}
+		// Local numbers with a global context are relative to it.
+		if !strings.HasPrefix(number, "+") && strings.HasPrefix(context, "+") {
+			number = context + number
+		}
+	} else {
+		number, extension = splitExtension(value)
+	}
+	digits, international, err := phoneDigits(number)
+	if err != nil {
+		return Phone{}, err
+	}
+	if extension, err = digitsOnly(extension); err != nil {
+		return Phone{}, err
+	}
+	region := strings.ToUpper(defaultRegion)
+	nanp := callingCodes[region] == 1
+	if !international {
+		switch {
+		case !nanp && strings.HasPrefix(digits, "00"):
+			digits, international = digits[2:], true
+		case nanp && strings.HasPrefix(digits, "011"):
+			digits, international = digits[3:], true
+		}
+	}
+	p := Phone{Extension: extension}
+	if international {
+		for n := 1; n <= 3 && n < len(digits); n++ {
+			code, _ := strconv.Atoi(digits[:n])
+			if knownCallingCode(code) {
+				p.CountryCode, p.National = code, digits[n:]
+				break
+			}
+		}
+		if p.CountryCode == 0 {
+			return Phone{}, ErrUnknownCallingCode
+		}
+	} else {
+		code, ok := callingCodes[region]
+		if !ok {
+			return Phone{}, ErrUnknownRegion
+		}
+		p.CountryCode, p.National = code, dropTrunkPrefix(digits, region)
+	}
+	if len(p.National) < minNationalDigits || len(strconv.Itoa(p.CountryCode))+len(p.National) > maxE164Digits {
+		return Phone{}, ErrBadPhoneNumber
+	}
+	return p, nil
+}
+
+// splitExtension separates a free text extension like "x123", "ext. 123"
+// or ";ext=123" from a number.
+func splitExtension(value string) (number, extension string) {
+	lower := strings.ToLower(value)
+	for _, marker := range []string{";ext=", "ext.", "ext", "x", "#"} {
+		if i := strings.LastIndex(lower, marker); i != -1 {
+			return value[:i], strings.TrimSpace(value[i+len(marker):])
+		}
+	}
+	return value, ""
+}
+
+// phoneDigits strips visual separators from a number, reporting whether
+// it began with "+".
+func phoneDigits(number string) (digits string, international bool, err error) {
+	number = strings.TrimSpace(number)
+	if strings.HasPrefix(number, "+") {
+		number, international = number[1:], true
+	}
+	digits, err = digitsOnly(number)
+	if err != nil || digits == "" {
+		return "", false, ErrBadPhoneNumber
+	}
+	return digits, international, nil
+}
+
+// digitsOnly drops the visual separators of RFC 3966 and spaces, failing on
+// anything else that isn't a digit.
+func digitsOnly(s string) (string, error) {
+	var digits []rune
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = append(digits, r)
+		case strings.ContainsRune(" -.()/", r):
+		default:
+			return "", ErrBadPhoneNumber
+		}
+	}
+	return string(digits), nil
+}
+
+func dropTrunkPrefix(digits, region string) string {
+	switch {
+	case callingCodes[region] == 1:
+		if len(digits) == 11 && digits[0] == '1' {
+			return digits[1:]
+		}
+	case !noTrunkPrefix[region]:
+		if strings.HasPrefix(digits, "0") {
+			return digits[1:]
+		}
+	}
+	return digits
+}
+
+// E164 formats the number, without extension, as "+" and digits.
+func (p Phone) E164() string {
+	return "+" + strconv.Itoa(p.CountryCode) + p.National
+}
+
+// URI formats the number as a global tel: URI.
+func (p Phone) URI() string {
+	if p.Extension == "" {
+		return "tel:" + p.E164()
+	}
+	return "tel:" + p.E164() + ";ext=" + p.Extension
+}
+
+// String formats the number as text, the extension following an " x".
+func (p Phone) String() string {
+	if p.Extension == "" {
+		return p.E164()
+	}
+	return p.E164() + " x" + p.Extension
+}
+
+// Phone parses the value of a TEL datum with ParsePhone.
+func (datum VcardDatum) Phone(defaultRegion string) (Phone, error) {
+	return ParsePhone(datum.StringValue, defaultRegion)
+}
+
+// PhoneDatum makes a TEL datum for the number in the form version expects:
+// a tel: URI with VALUE=uri for "4.0", or otherwise E.164 text. attrs is
+// copied, not changed.
+func PhoneDatum(p Phone, attrs AttrMap, version string) VcardDatum {
+	d := StringDatum("TEL", copyAttrs(attrs), p.String())
+	if version == "4.0" {
+		d.StringValue = p.URI()
+		d.setParam("VALUE", "uri")
+	} else {
+		d.setParam("VALUE")
+	}
+	return d
+}
@@ -0,0 +1,65 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type phoneTC struct {
+	Value, Region string
+}
+
+var (
+	phoneTCs = map[phoneTC]Phone{
+		{"tel:+14045551212", ""}:                       {1, "4045551212", ""},
+		{"tel:+1-201-555-0123;ext=1234", ""}:           {1, "2015550123", "1234"},
+		{"tel:7042;phone-context=+1-404-555", ""}:      {1, "4045557042", ""},
+		{"(404) 555-1212", "US"}:                       {1, "4045551212", ""},
+		{"1 404 555 1212 ext. 12", "us"}:               {1, "4045551212", "12"},
+		{"011 44 20 7946 0958", "US"}:                  {44, "2079460958", ""},
+		{"+44 20 7946 0958", ""}:                       {44, "2079460958", ""},
+		{"020 7946 0958", "GB"}:                        {44, "2079460958", ""},
+		{"00 353 1 234 5678", "DE"}:                    {353, "12345678", ""},
+		{"06 12 34 56 78 x9", "IT"}:                    {39, "0612345678", "9"},
+		{"+7 (495) 123-45-67", ""}:                     {7, "4951234567", ""},
+		{"tel:+359-2-123-4567;phone-context=+359", ""}: {359, "21234567", ""},
+	}
+
+	badPhoneTCs = map[phoneTC]error{
+		{"1-800-FLOWERS", "US"}: ErrBadPhoneNumber,
+		{"555 1212", ""}:        ErrUnknownRegion,
+		{"+999 1234 5678", ""}:  ErrUnknownCallingCode,
+		{"+1 22", ""}:           ErrBadPhoneNumber,
+	}
+)
+
+func TestParsePhone(t *testing.T) {
+	for tc, expected := range phoneTCs {
+		p, err := ParsePhone(tc.Value, tc.Region)
+		assert.Nil(t, err, tc.Value)
+		assert.Equal(t, expected, p, tc.Value)
+	}
+	for tc, expected := range badPhoneTCs {
+		_, err := ParsePhone(tc.Value, tc.Region)
+		assert.Equal(t, expected, err, tc.Value)
+	}
+}
+
+func TestPhoneDatum(t *testing.T) {
+	d := StringDatum("TEL", AttrMap{"TYPE": []string{"work"}}, "(404) 555-1212 x5")
+	p, err := d.Phone("US")
+	assert.Nil(t, err)
+	assert.Equal(t, "+14045551212", p.E164())
+
+	v4 := PhoneDatum(p, d.Attrs, "4.0")
+	out, err := v4.Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "TEL;VALUE=uri;TYPE=work:tel:+14045551212;ext=5\n", out)
+
+	v3 := PhoneDatum(p, v4.Attrs, "3.0")
+	out, err = v3.Output(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "TEL;TYPE=work:+14045551212 x5\n", out)
+	assert.EqualValues(t, AttrMap{"TYPE": []string{"work"}}, d.Attrs)
+}
@@ -0,0 +1,113 @@
+package vcardenc
+
+import "strings"
+
+// Parameters of the Punycode bootstring, from RFC 3492 section 5.
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// toASCIIDomain lower-cases a domain and Punycode-encodes any non-ASCII
+// labels with the "xn--" prefix, so "Bücher.Example" becomes
+// "xn--bcher-kva.example". It doesn't do the full IDNA mapping.
+func toASCIIDomain(domain string) string {
+	labels := strings.Split(strings.ToLower(domain), ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			labels[i] = "xn--" + punycodeEncode([]rune(label))
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// punycodeEncode is the encoding procedure of RFC 3492 section 6.3.
+func punycodeEncode(input []rune) string {
+	var output []byte
+	for _, r := range input {
+		if r < 0x80 {
+			output = append(output, byte(r))
+		}
+	}
+	basic := len(output)
+	handled := basic
+	if basic > 0 {
+		output = append(output, '-')
+	}
+	n, delta, bias := rune(punyInitialN), 0, punyInitialBias
+	for handled < len(input) {
+		m := rune(0x7fffffff)
+		for _, r := range input {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+		for _, r := range input {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := k - bias
+				if t < punyTMin {
+					t = punyTMin
+				} else if t > punyTMax {
+					t = punyTMax
+				}
+				if q < t {
+					break
+				}
+				output = append(output, punyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			output = append(output, punyDigit(q))
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(output)
+}
+
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+func punyAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
@@ -0,0 +1,28 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// punycodeTCs are sample strings from RFC 3492 section 7.1. The Russian
+// one drops its mixed-case annotation, which punycodeEncode doesn't write.
+var punycodeTCs = map[string]string{
+	"ليهمابتكلموشعربي؟":            "egbpdaj6bu4bxfgehfvwxn",
+	"他们为什么不说中文":                    "ihqwcrb4cv8a8dqg056pqjye",
+	"他們爲什麽不說中文":                    "ihqwctvzc91f659drss3x8bo0yb",
+	"Pročprostěnemluvíčesky":       "Proprostnemluvesky-uyb24dma41a",
+	"למההםפשוטלאמדבריםעברית":       "4dbcagdahymbxekheh6e0a7fei0b",
+	"почемужеонинеговорятпорусски": "b1abfaaepdrnnbgefbadotcwatmq2g4l",
+	"3年B組金八先生":                     "3B-ww4c5e180e575a65lsy2b",
+	"安室奈美恵-with-SUPER-MONKEYS":     "-with-SUPER-MONKEYS-pc58ag80a8qai00g7n9n",
+	"そのスピードで":                      "d9juau41awczczp",
+	"-> $1.00 <-":                  "-> $1.00 <--",
+}
+
+func TestPunycodeEncode(t *testing.T) {
+	for input, expected := range punycodeTCs {
+		assert.Equal(t, expected, punycodeEncode([]rune(input)), input)
+	}
+}
@@ -0,0 +1,91 @@
+package vcardenc
+
+import (
+	"sort"
+	"strings"
+)
+
+// DatumFilter selects data in Vcard queries.
+type DatumFilter func(VcardDatum) bool
+
+// WithType selects data having all the given TYPE values, ignoring case.
+func WithType(types ...string) DatumFilter {
+	return func(d VcardDatum) bool {
+		for _, t := range types {
+			if !d.HasType(t) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// WithParam selects data whose parameter key has the given value, ignoring case.
+func WithParam(key, value string) DatumFilter {
+	return func(d VcardDatum) bool {
+		for _, v := range d.Attrs.Get(key) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GetAll returns the data named fieldName, ignoring case, that pass every
+// filter, in document order.
+func (v Vcard) GetAll(fieldName string, filters ...DatumFilter) (found []VcardDatum) {
+	for _, d := range v.Data {
+		if !strings.EqualFold(d.FieldName, fieldName) {
+			continue
+		}
+		if passesFilters(d, filters) {
+			found = append(found, d)
+		}
+	}
+	return found
+}
+
+// Get returns the first datum named fieldName that passes every filter.
+func (v Vcard) Get(fieldName string, filters ...DatumFilter) (VcardDatum, bool) {
+	found := v.GetAll(fieldName, filters...)
+	if len(found) == 0 {
+		return emptyDatum, false
+	}
+	return found[0], true
+}
+
+// Preferred returns the most preferred datum named fieldName that passes
+// every filter: the one with the lowest PREF parameter, counting a vCard 3.0
+// style TYPE=pref as PREF=1, and then the earliest in the card.
+func (v Vcard) Preferred(fieldName string, filters ...DatumFilter) (VcardDatum, bool) {
+	found := v.GetAll(fieldName, filters...)
+	if len(found) == 0 {
+		return emptyDatum, false
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].preference() < found[j].preference()
+	})
+	return found[0], true
+}
+
+// preference ranks a datum for Preferred; lower is more preferred and data
+// without any preference rank after everything else.
+func (datum VcardDatum) preference() int {
+	if pref, ok := datum.Pref(); ok {
+		return pref
+	}
+	if datum.HasType("pref") {
+		return 1
+	}
+	return 101
+}
+
+func passesFilters(d VcardDatum, filters []DatumFilter) bool {
+	for _, f := range filters {
+		if !f(d) {
+			return false
+		}
+	}
+	return true
+}
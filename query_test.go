@@ -0,0 +1,48 @@
+package vcardenc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	phoneCard = Vcard{
+		Data: []VcardDatum{
+			StringDatum("FN", nil, "Forrest Gump"),
+			StringDatum("TEL", AttrMap{"TYPE": []string{"work", "voice"}}, "tel:+11115551212"),
+			StringDatum("TEL", AttrMap{"TYPE": []string{"home"}, "PREF": []string{"2"}}, "tel:+14045551212"),
+			StringDatum("tel", AttrMap{"TYPE": []string{"WORK", "cell"}, "PREF": []string{"1"}}, "tel:+11115551313"),
+			StringDatum("EMAIL", AttrMap{"TYPE": []string{"home"}}, "forrest@example.com"),
+			StringDatum("EMAIL", AttrMap{"TYPE": []string{"work", "pref"}}, "forrestgump@example.com"),
+		},
+	}
+)
+
+func TestGetAll(t *testing.T) {
+	assert.Len(t, phoneCard.GetAll("TEL"), 3)
+	assert.Len(t, phoneCard.GetAll("tel", WithType("work")), 2)
+	assert.Len(t, phoneCard.GetAll("TEL", WithType("work", "cell")), 1)
+	assert.Len(t, phoneCard.GetAll("TEL", WithParam("pref", "2")), 1)
+	assert.Empty(t, phoneCard.GetAll("ADR"))
+
+	d, ok := phoneCard.Get("TEL", WithType("work"))
+	assert.True(t, ok)
+	assert.Equal(t, "tel:+11115551212", d.StringValue)
+	_, ok = phoneCard.Get("TEL", WithType("fax"))
+	assert.False(t, ok)
+}
+
+func TestPreferred(t *testing.T) {
+	d, ok := phoneCard.Preferred("TEL")
+	assert.True(t, ok)
+	assert.Equal(t, "tel:+11115551313", d.StringValue)
+	d, ok = phoneCard.Preferred("TEL", WithType("home"))
+	assert.True(t, ok)
+	assert.Equal(t, "tel:+14045551212", d.StringValue)
+	d, ok = phoneCard.Preferred("EMAIL")
+	assert.True(t, ok)
+	assert.Equal(t, "forrestgump@example.com", d.StringValue)
+	_, ok = phoneCard.Preferred("URL")
+	assert.False(t, ok)
+}
@@ -0,0 +1,309 @@
+package vcardenc
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Cardinality is how many times a property may appear in a card, using the
+// notation of RFC 6350 section 6.
+type Cardinality string
+
+const (
+	// CardinalityExactlyOne properties must appear once and only once.
+	CardinalityExactlyOne Cardinality = "1"
+	// CardinalityAtMostOne properties are optional but may not repeat.
+	CardinalityAtMostOne Cardinality = "*1"
+	// CardinalityAtLeastOne properties must appear and may repeat.
+	CardinalityAtLeastOne Cardinality = "1*"
+	// CardinalityAny properties may appear any number of times.
+	CardinalityAny Cardinality = "*"
+)
+
+var (
+	// ErrCardinality is returned by Validate when a property appears more
+	// or fewer times than its PropertySpec allows.
+	ErrCardinality = errors.New("Property appears an unexpected number of times")
+
+	// ErrParamNotAllowed is returned by Validate when a datum carries a
+	// parameter its PropertySpec doesn't list.
+	ErrParamNotAllowed = errors.New("Parameter not allowed on this property")
+)
+
+// PropertyError ties a validation error to the property that caused it.
+type PropertyError struct {
+	FieldName string
+	Err       error
+}
+
+func (e PropertyError) Error() string {
+	return e.FieldName + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error, so errors.Is works on PropertyErrors.
+func (e PropertyError) Unwrap() error {
+	return e.Err
+}
+
+// PropertySpec is what this library knows about a single property.
+type PropertySpec struct {
+	// Name is the property name, like "ADR" or "X-SOCIALPROFILE".
+	Name string
+
+	// ValueType is the default valueType the property is parsed as.
+	ValueType valueType
+
+	// Cardinality is how many times the property may appear in a card.
+	// Empty is treated as CardinalityAny.
+	Cardinality Cardinality
+
+	// URI is set if the property's value is a URI unless a VALUE parameter
+	// says otherwise. URIs are written and parsed without text escaping.
+	URI bool
+
+	// Components is the number of components a structured value must be
+	// written with, like the seven of ADR, or zero if it varies or trailing
+	// components are optional, as the identity of GENDER is. Values are
+	// padded to this many but may carry more, as RFC 9554 adds components
+	// to ADR and N.
+	Components int
+
+	// Params lists the parameters allowed on the property. If nil, any
+	// parameter is allowed. X- parameters are always allowed.
+	Params []string
+
+	// Encoder, if set, replaces the default encoding of the property. It
+	// must not encode its datum with the same registry, or it recurses.
+	Encoder DatumEncoder
+
+	// Decoder, if set, replaces the default parsing of the property.
+	Decoder DatumDecoder
+
+	// Validator, if set, checks the value of the property in Validate.
+	Validator func(VcardDatum) error
+}
+
+// AllowsParam reports whether the named parameter may appear on the property,
+// ignoring case.
+func (spec PropertySpec) AllowsParam(name string) bool {
+	name = strings.ToUpper(name)
+	if spec.Params == nil || strings.HasPrefix(name, "X-") || stringSliceContains(anyPropertyParams, name) {
+		return true
+	}
+	return stringSliceContains(spec.Params, name)
+}
+
+// Registry maps property names to their PropertySpecs. The zero value is
+// not usable; make one with NewRegistry. A Registry is safe to use from
+// several goroutines, Register included.
+type Registry struct {
+	mu    sync.RWMutex
+	specs map[string]PropertySpec
+}
+
+// NewRegistry returns a Registry holding the given specs.
+func NewRegistry(specs ...PropertySpec) *Registry {
+	r := &Registry{specs: make(map[string]PropertySpec, len(specs))}
+	for _, spec := range specs {
+		r.Register(spec)
+	}
+	return r
+}
+
+// Register adds spec to the registry, replacing any existing spec of the
+// same name. Names are case-insensitive and stored in upper case.
+func (r *Registry) Register(spec PropertySpec) {
+	spec.Name = strings.ToUpper(spec.Name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.specs[spec.Name] = spec
+}
+
+// Lookup returns the spec registered for fieldName, ignoring case.
+func (r *Registry) Lookup(fieldName string) (PropertySpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, ok := r.specs[strings.ToUpper(fieldName)]
+	return spec, ok
+}
+
+// isURIValued reports whether a string value of the property is a URI,
+// going by its VALUE parameter or else its spec.
+func (r *Registry) isURIValued(fieldName string, attrs AttrMap) bool {
+	if value := attrs.first("VALUE"); value != "" {
+		return strings.EqualFold(value, "uri")
+	}
+	spec, ok := r.Lookup(fieldName)
+	return ok && spec.URI
+}
+
+// Clone returns a copy of the registry that can be extended without
+// changing the original, which is handy for building on DefaultRegistry.
+func (r *Registry) Clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &Registry{specs: make(map[string]PropertySpec, len(r.specs))}
+	for name, spec := range r.specs {
+		c.specs[name] = spec
+	}
+	return c
+}
+
+// Encoders returns the custom encoders of the registry, in the form
+// expected by Vcard.Encode and VcardDatum.Output. Encoding with the
+// registry as EncodeOptions.Registry applies them without this.
+func (r *Registry) Encoders() map[string]DatumEncoder {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	encoders := make(map[string]DatumEncoder)
+	for name, spec := range r.specs {
+		if spec.Encoder != nil {
+			encoders[name] = spec.Encoder
+		}
+	}
+	return encoders
+}
+
+// Validate checks the data of a card against the cardinality, parameters
+// and Validators of their specs, counting data that share an ALTID as one
+// for cardinality. Properties without a spec are not checked, and neither
+// are BEGIN, VERSION and END. Errors come in the order of Data, then those
+// of cardinality by property name.
+func (r *Registry) Validate(v Vcard) (errs []error) {
+	v = v.SyncData()
+	counts := make(map[string]int)
+	altIDs := make(map[string]bool)
+	for _, d := range v.Data {
+		spec, ok := r.Lookup(d.FieldName)
+		if !ok {
+			continue
+		}
+		// Alternative representations sharing an ALTID count only once.
+		if altID := d.AltID(); altID == "" || !altIDs[spec.Name+";"+altID] {
+			counts[spec.Name]++
+			if altID != "" {
+				altIDs[spec.Name+";"+altID] = true
+			}
+		}
+		for _, key := range d.Attrs.sortedKeys() {
+			if !spec.AllowsParam(key) {
+				errs = append(errs, PropertyError{d.FieldName, ErrParamNotAllowed})
+			}
+		}
+		if spec.Validator != nil {
+			if err := spec.Validator(d); err != nil {
+				errs = append(errs, PropertyError{d.FieldName, err})
+			}
+		}
+	}
+	for _, spec := range r.sortedSpecs() {
+		name := spec.Name
+		// Encode writes these itself, so they aren't expected in Data.
+		if name == "BEGIN" || name == "VERSION" || name == "END" {
+			continue
+		}
+		if !spec.Cardinality.allows(counts[name]) {
+			errs = append(errs, PropertyError{name, ErrCardinality})
+		}
+	}
+	return errs
+}
+
+// sortedSpecs returns the specs of the registry in order of name.
+func (r *Registry) sortedSpecs() []PropertySpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	specs := make([]PropertySpec, 0, len(r.specs))
+	for _, spec := range r.specs {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// singular reports whether the property may appear at most once.
+func (c Cardinality) singular() bool {
+	return c == CardinalityExactlyOne || c == CardinalityAtMostOne
+}
+
+func (c Cardinality) allows(count int) bool {
+	switch c {
+	case CardinalityExactlyOne:
+		return count == 1
+	case CardinalityAtMostOne:
+		return count <= 1
+	case CardinalityAtLeastOne:
+		return count >= 1
+	}
+	return true
+}
+
+var (
+	// Parameter lists from the ABNF of RFC 6350 section 6.
+	uriParams   = []string{"VALUE", "PID", "PREF", "TYPE", "MEDIATYPE", "ALTID"}
+	textParams  = []string{"VALUE", "TYPE", "LANGUAGE", "ALTID", "PID", "PREF"}
+	mediaParams = []string{"VALUE", "LANGUAGE", "ALTID", "PID", "PREF", "TYPE", "MEDIATYPE"}
+	dateParams  = []string{"VALUE", "ALTID", "CALSCALE", "LANGUAGE"}
+	valueParam  = []string{"VALUE"}
+
+	// anyPropertyParams are the parameters RFC 9554 allows on every property.
+	anyPropertyParams = []string{"AUTHOR", "AUTHOR-NAME", "CREATED", "DERIVED", "PROP-ID"}
+
+	// socialParams are those of IMPP and SOCIALPROFILE, from RFC 9554.
+	socialParams = append(append([]string(nil), uriParams...), "SERVICE-TYPE", "USERNAME")
+
+	// DefaultRegistry knows the properties of RFC 6350 and RFC 9554 and is
+	// used by ParseDatumLine. Register extensions on it, or on a Clone of it;
+	// registering is safe while cards are parsed and encoded elsewhere, but
+	// they may or may not see the new spec until it returns.
+	DefaultRegistry = NewRegistry(
+		PropertySpec{Name: "BEGIN", ValueType: StringValueType, Cardinality: CardinalityExactlyOne, Params: []string{}},
+		PropertySpec{Name: "END", ValueType: StringValueType, Cardinality: CardinalityExactlyOne, Params: []string{}},
+		PropertySpec{Name: "VERSION", ValueType: StringValueType, Cardinality: CardinalityExactlyOne, Params: []string{}},
+		PropertySpec{Name: "SOURCE", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "KIND", ValueType: StringValueType, Validator: validateKind, Cardinality: CardinalityAtMostOne, Params: valueParam},
+		PropertySpec{Name: "XML", ValueType: StringValueType, Cardinality: CardinalityAny, Params: []string{"VALUE", "ALTID"}},
+		PropertySpec{Name: "FN", ValueType: StringValueType, Cardinality: CardinalityAtLeastOne, Params: textParams},
+		PropertySpec{Name: "N", ValueType: SemicolonStructuredValueType, Components: 5, Cardinality: CardinalityAtMostOne, Params: []string{"VALUE", "SORT-AS", "LANGUAGE", "ALTID", "PHONETIC", "SCRIPT"}},
+		PropertySpec{Name: "NICKNAME", ValueType: CommaStructuredValueType, Cardinality: CardinalityAny, Params: textParams},
+		PropertySpec{Name: "PHOTO", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "BDAY", ValueType: StringValueType, Cardinality: CardinalityAtMostOne, Params: dateParams},
+		PropertySpec{Name: "ANNIVERSARY", ValueType: StringValueType, Cardinality: CardinalityAtMostOne, Params: dateParams},
+		PropertySpec{Name: "GENDER", ValueType: SemicolonStructuredValueType, Validator: validateGender, Cardinality: CardinalityAtMostOne, Params: valueParam},
+		PropertySpec{Name: "ADR", ValueType: SemicolonStructuredValueType, Components: 7, Cardinality: CardinalityAny, Params: []string{"VALUE", "LABEL", "LANGUAGE", "GEO", "TZ", "ALTID", "PID", "PREF", "TYPE", "PHONETIC", "SCRIPT"}},
+		PropertySpec{Name: "TEL", ValueType: StringValueType, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "EMAIL", ValueType: StringValueType, Validator: validateEmail, Cardinality: CardinalityAny, Params: []string{"VALUE", "PID", "PREF", "TYPE", "ALTID"}},
+		PropertySpec{Name: "IMPP", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: socialParams},
+		PropertySpec{Name: "LANG", ValueType: StringValueType, Cardinality: CardinalityAny, Params: []string{"VALUE", "PID", "PREF", "ALTID", "TYPE"}},
+		PropertySpec{Name: "TZ", ValueType: StringValueType, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "GEO", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "TITLE", ValueType: StringValueType, Cardinality: CardinalityAny, Params: textParams},
+		PropertySpec{Name: "ROLE", ValueType: StringValueType, Cardinality: CardinalityAny, Params: textParams},
+		PropertySpec{Name: "LOGO", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: mediaParams},
+		PropertySpec{Name: "ORG", ValueType: SemicolonStructuredValueType, Cardinality: CardinalityAny, Params: []string{"VALUE", "SORT-AS", "LANGUAGE", "PID", "PREF", "ALTID", "TYPE", "PHONETIC", "SCRIPT"}},
+		PropertySpec{Name: "MEMBER", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: []string{"VALUE", "PID", "PREF", "ALTID", "MEDIATYPE"}},
+		PropertySpec{Name: "RELATED", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: mediaParams},
+		PropertySpec{Name: "CATEGORIES", ValueType: CommaStructuredValueType, Cardinality: CardinalityAny, Params: []string{"VALUE", "PID", "PREF", "TYPE", "ALTID"}},
+		PropertySpec{Name: "NOTE", ValueType: StringValueType, Cardinality: CardinalityAny, Params: textParams},
+		PropertySpec{Name: "PRODID", ValueType: StringValueType, Cardinality: CardinalityAtMostOne, Params: valueParam},
+		PropertySpec{Name: "REV", ValueType: StringValueType, Cardinality: CardinalityAtMostOne, Params: valueParam},
+		PropertySpec{Name: "SOUND", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: mediaParams},
+		PropertySpec{Name: "UID", ValueType: StringValueType, Cardinality: CardinalityAtMostOne, Params: valueParam},
+		PropertySpec{Name: "CLIENTPIDMAP", ValueType: SemicolonStructuredValueType, Components: 2, Cardinality: CardinalityAny, Params: []string{}},
+		PropertySpec{Name: "URL", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "KEY", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "FBURL", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "CALADRURI", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+		PropertySpec{Name: "CALURI", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: uriParams},
+
+		// RFC 9554 extensions.
+		PropertySpec{Name: "CREATED", ValueType: StringValueType, Validator: validateTimestamp, Cardinality: CardinalityAtMostOne, Params: valueParam},
+		PropertySpec{Name: "GRAMGENDER", ValueType: StringValueType, Validator: validateGramGender, Cardinality: CardinalityAny, Params: []string{"LANGUAGE"}},
+		PropertySpec{Name: "LANGUAGE", ValueType: StringValueType, Cardinality: CardinalityAtMostOne, Params: []string{}},
+		PropertySpec{Name: "PRONOUNS", ValueType: StringValueType, Cardinality: CardinalityAny, Params: []string{"VALUE", "LANGUAGE", "PREF", "TYPE", "ALTID"}},
+		PropertySpec{Name: "SOCIALPROFILE", ValueType: StringValueType, URI: true, Cardinality: CardinalityAny, Params: socialParams},
+		PropertySpec{Name: "JSPROP", ValueType: StringValueType, Cardinality: CardinalityAny, Params: []string{"VALUE", "JSPTR"}},
+	)
+)
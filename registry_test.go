@@ -0,0 +1,144 @@
+package vcardenc
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryExtension(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	r.Register(PropertySpec{Name: "X-CHILDREN", ValueType: CommaStructuredValueType})
+	parsed, err := r.ParseDatumLine("X-CHILDREN:Alice,Bob")
+	assert.Nil(t, err)
+	assert.EqualValues(t, CommaStructuredDatum("X-CHILDREN", nil, "Alice", "Bob"), parsed)
+
+	// The default registry is untouched.
+	parsed, err = ParseDatumLine("X-CHILDREN:Alice,Bob")
+	assert.Nil(t, err)
+	assert.EqualValues(t, StringDatum("X-CHILDREN", nil, "Alice,Bob"), parsed)
+}
+
+func TestRegistryEncoderDecoder(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	r.Register(PropertySpec{
+		Name:      "X-SHOUT",
+		ValueType: StringValueType,
+		Encoder: func(d VcardDatum) (string, error) {
+			return d.FieldName + ":" + strings.ToUpper(d.StringValue) + "!\n", nil
+		},
+		Decoder: func(fieldName string, attrs AttrMap, rawValue string) (VcardDatum, error) {
+			return StringDatum(fieldName, attrs, strings.ToLower(strings.TrimSuffix(rawValue, "!"))), nil
+		},
+	})
+	card := "BEGIN:VCARD\nVERSION:4.0\nFN:Jane\nX-SHOUT:HELLO!\nEND:VCARD"
+	v, err := r.ParseVcard(card, nil)
+	assert.Nil(t, err)
+	shout, _ := v.Get("X-SHOUT")
+	assert.Equal(t, "hello", shout.StringValue)
+	out, err := v.EncodeWith(EncodeOptions{Registry: r})
+	assert.Nil(t, err)
+	assert.Equal(t, card, out)
+
+	// Special rules still come first.
+	out, err = shout.OutputWith(EncodeOptions{Registry: r, SpecialRules: map[string]DatumEncoder{
+		"X-SHOUT": func(d VcardDatum) (string, error) { return "X-SHOUT:quiet\n", nil },
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, "X-SHOUT:quiet\n", out)
+}
+
+func TestRegistryValidate(t *testing.T) {
+	errs := DefaultRegistry.Validate(wikipediaCardTestCase)
+	assert.Empty(t, errs)
+
+	bad := Vcard{Data: []VcardDatum{
+		StringDatum("UID", nil, "urn:uuid:1"),
+		StringDatum("UID", nil, "urn:uuid:2"),
+		StringDatum("KIND", AttrMap{"TYPE": []string{"work"}, "X-FOO": []string{"bar"}}, "individual"),
+	}}
+	errs = DefaultRegistry.Validate(bad)
+	assert.Equal(t, []error{
+		PropertyError{"KIND", ErrParamNotAllowed},
+		PropertyError{"FN", ErrCardinality},
+		PropertyError{"UID", ErrCardinality},
+	}, errs)
+}
+
+func TestRegistryConcurrentRegister(t *testing.T) {
+	r := DefaultRegistry.Clone()
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			r.Register(PropertySpec{Name: "X-COUNT-" + strconv.Itoa(i), ValueType: StringValueType})
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		_, err := r.ParseDatumLine("FN:Jane Doe")
+		assert.Nil(t, err)
+		r.Validate(wikipediaCardTestCase)
+	}
+	<-done
+	_, ok := r.Lookup("X-COUNT-99")
+	assert.True(t, ok)
+}
+
+func TestRFC9554Properties(t *testing.T) {
+	card, err := ParseVcard(`BEGIN:VCARD
+VERSION:4.0
+FN;DERIVED=TRUE:Jane Doe
+N;PHONETIC=ipa;SCRIPT=Latn:Doe;Jane;;;;Smith;III
+CREATED:20220705T093412Z
+LANGUAGE:de-AT
+GRAMGENDER:feminine
+PRONOUNS;PREF=1:she/her
+SOCIALPROFILE;SERVICE-TYPE=Mastodon;USERNAME="@jane@example.com":https://example.com/@jane
+SOCIALPROFILE;VALUE=text;SERVICE-TYPE=SomeSite:peter94
+JSPROP;JSPTR="a/b":{"c"\;1}
+NOTE;AUTHOR="mailto:boss@example.com";AUTHOR-NAME="Boss, The";PROP-ID=n1;CREATED=20230101T000000Z:Nice
+END:VCARD`, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, DefaultRegistry.Validate(card))
+
+	fn, _ := card.Get("FN")
+	assert.True(t, fn.Derived())
+
+	n, _ := card.Get("N")
+	assert.Equal(t, "ipa", n.Phonetic())
+	assert.Equal(t, "Latn", n.Script())
+	assert.Equal(t, []string{"Smith"}, n.Name().SecondaryFamilyNames)
+
+	created, _ := card.Get("CREATED")
+	ts, err := created.Timestamp()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, 7, 5, 9, 34, 12, 0, time.UTC), ts)
+
+	profiles := card.GetAll("SOCIALPROFILE")
+	assert.Len(t, profiles, 2)
+	assert.Equal(t, "Mastodon", profiles[0].ServiceType())
+	assert.Equal(t, "@jane@example.com", profiles[0].Username())
+	assert.Equal(t, "peter94", profiles[1].StringValue)
+
+	jsprop, _ := card.Get("JSPROP")
+	assert.Equal(t, `{"c";1}`, jsprop.StringValue)
+
+	note, _ := card.Get("NOTE")
+	assert.Equal(t, "mailto:boss@example.com", note.Author())
+	assert.Equal(t, "Boss, The", note.AuthorName())
+	assert.Equal(t, "n1", note.PropID())
+	noteCreated, ok := note.CreatedParam()
+	assert.True(t, ok)
+	assert.Equal(t, 2023, noteCreated.Year())
+
+	bad := Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Jane Doe"),
+		StringDatum("GRAMGENDER", nil, "plural?"),
+		StringDatum("CREATED", nil, "last tuesday"),
+	}}
+	errs := DefaultRegistry.Validate(bad)
+	assert.Len(t, errs, 2)
+}
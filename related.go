@@ -0,0 +1,108 @@
+package vcardenc
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Relation is a RELATED datum of Cards[From] in an AddressBook.
+type Relation struct {
+	From int
+	// To is the index of the card the relation refers to, or -1 if it's
+	// free text or a URI outside the AddressBook.
+	To int
+	// Value is the URI, or with VALUE=text the free text, of the RELATED.
+	Value string
+	// Types are the TYPE values, like "spouse" or "colleague", in lower case.
+	Types []string
+}
+
+// Relations returns every RELATED of every card in the AddressBook, in
+// order, resolving URIs as for MEMBER.
+func (ab *AddressBook) Relations() (relations []Relation) {
+	for i := range ab.Cards {
+		relations = append(relations, ab.Related(i)...)
+	}
+	return relations
+}
+
+// Related returns the relations of Cards[i] having all the given types,
+// ignoring case, like Related(i, "child") for their children.
+func (ab *AddressBook) Related(i int, types ...string) (relations []Relation) {
+	for _, d := range ab.Cards[i].GetAll("RELATED", WithType(types...)) {
+		rel := Relation{From: i, To: -1, Value: d.StringValue, Types: d.Types()}
+		if !strings.EqualFold(d.Attrs.first("VALUE"), "text") {
+			if to, ok := ab.lookupIndex(d.StringValue); ok {
+				rel.To = to
+			}
+		}
+		relations = append(relations, rel)
+	}
+	return relations
+}
+
+// Relatives returns the cards Cards[i] is related to with all the given
+// types, skipping relations that don't resolve to a card.
+func (ab *AddressBook) Relatives(i int, types ...string) (relatives []Vcard) {
+	for _, rel := range ab.Related(i, types...) {
+		if rel.To != -1 {
+			relatives = append(relatives, ab.Cards[rel.To])
+		}
+	}
+	return relatives
+}
+
+// WriteDOT writes the relationship graph as a Graphviz digraph. Cards are
+// labelled by their FN, and relations that don't resolve to a card point
+// at dashed boxes labelled with their value.
+func (ab *AddressBook) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph related {\n")
+	relations := ab.Relations()
+	linked := make(map[int]bool)
+	for _, rel := range relations {
+		linked[rel.From] = true
+		if rel.To != -1 {
+			linked[rel.To] = true
+		}
+	}
+	for i, v := range ab.Cards {
+		if linked[i] {
+			bw.WriteString("\tcard" + strconv.Itoa(i) + " [label=" + dotQuote(cardLabel(v, i)) + "];\n")
+		}
+	}
+	for n, rel := range relations {
+		to := "card" + strconv.Itoa(rel.To)
+		if rel.To == -1 {
+			to = "other" + strconv.Itoa(n)
+			bw.WriteString("\t" + to + " [label=" + dotQuote(rel.Value) + ", shape=box, style=dashed];\n")
+		}
+		bw.WriteString("\tcard" + strconv.Itoa(rel.From) + " -> " + to)
+		if len(rel.Types) > 0 {
+			bw.WriteString(" [label=" + dotQuote(strings.Join(rel.Types, ", ")) + "]")
+		}
+		bw.WriteString(";\n")
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// dotQuote quotes s as a DOT string, in which only quotes and backslashes
+// need escaping.
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
+
+// cardLabel names a card for humans: its FN, or UID, or failing those
+// its index.
+func cardLabel(v Vcard, i int) string {
+	for _, name := range []string{"FN", "UID"} {
+		if d, ok := v.Preferred(name); ok && d.StringValue != "" {
+			return d.StringValue
+		}
+	}
+	return "#" + strconv.Itoa(i)
+}
@@ -0,0 +1,72 @@
+package vcardenc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var relatedCards = `BEGIN:VCARD
+VERSION:4.0
+UID:urn:uuid:03a0e51f-d1aa-4385-8a08-d91e8e2c9b1a
+FN:Jane Doe
+RELATED;TYPE=spouse:mailto:john.doe@example.com
+RELATED;TYPE=child:urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6
+RELATED;VALUE=text;TYPE=co-worker,friend:Please contact my assistant Jane Doe for any inquiries.
+END:VCARD
+BEGIN:VCARD
+VERSION:4.0
+FN:John Doe
+EMAIL:john.doe@example.com
+RELATED;TYPE=spouse:urn:uuid:03a0e51f-d1aa-4385-8a08-d91e8e2c9b1a
+END:VCARD
+BEGIN:VCARD
+VERSION:4.0
+UID:urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6
+FN:Baby "Junior" Doe
+END:VCARD
+BEGIN:VCARD
+VERSION:4.0
+FN:Unrelated Person
+END:VCARD`
+
+func TestRelations(t *testing.T) {
+	cards, err := ParseVcards(relatedCards, nil)
+	assert.Nil(t, err)
+	ab := NewAddressBook(cards)
+
+	assert.Equal(t, []Relation{
+		{0, 1, "mailto:john.doe@example.com", []string{"spouse"}},
+		{0, 2, "urn:uuid:f81d4fae-7dec-11d0-a765-00a0c91e6bf6", []string{"child"}},
+		{0, -1, "Please contact my assistant Jane Doe for any inquiries.", []string{"co-worker", "friend"}},
+		{1, 0, "urn:uuid:03a0e51f-d1aa-4385-8a08-d91e8e2c9b1a", []string{"spouse"}},
+	}, ab.Relations())
+
+	assert.Equal(t, []Vcard{cards[2]}, ab.Relatives(0, "CHILD"))
+	assert.Equal(t, []Vcard{cards[0]}, ab.Relatives(1, "spouse"))
+	assert.Len(t, ab.Related(0, "friend"), 1)
+	assert.Empty(t, ab.Relatives(3))
+}
+
+func TestWriteDOT(t *testing.T) {
+	cards, err := ParseVcards(relatedCards, nil)
+	assert.Nil(t, err)
+	var buf bytes.Buffer
+	assert.Nil(t, NewAddressBook(cards).WriteDOT(&buf))
+	assert.Equal(t, `digraph related {
+	card0 [label="Jane Doe"];
+	card1 [label="John Doe"];
+	card2 [label="Baby \"Junior\" Doe"];
+	card0 -> card1 [label="spouse"];
+	card0 -> card2 [label="child"];
+	other2 [label="Please contact my assistant Jane Doe for any inquiries.", shape=box, style=dashed];
+	card0 -> other2 [label="co-worker, friend"];
+	card1 -> card0 [label="spouse"];
+}
+`, buf.String())
+}
+
+func TestDOTQuote(t *testing.T) {
+	assert.Equal(t, `"Zoë \"Z\" O'Brien \\ Ltd"`, dotQuote(`Zoë "Z" O'Brien \ Ltd`))
+}
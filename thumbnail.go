@@ -0,0 +1,213 @@
+package vcardenc
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	// Registered for image.Decode.
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+var (
+	// ErrPhotoTooLarge is returned when a photo can't be squeezed into the
+	// byte budget of its PhotoLimits.
+	ErrPhotoTooLarge = errors.New("Photo can't be shrunk within its byte budget")
+
+	// ErrTooManyPixels is returned when an image declares more pixels than
+	// its PhotoLimits allow to be decoded.
+	ErrTooManyPixels = errors.New("Image has too many pixels to decode")
+)
+
+// PhotoLimits bound the size of embedded images. Zero fields don't limit,
+// except MaxPixels.
+type PhotoLimits struct {
+	// MaxDimension is the most pixels either side of an image may have.
+	MaxDimension int
+	// MaxBytes is the most an encoded image may weigh, before base64.
+	MaxBytes int
+	// MaxPixels is the most pixels, width times height, an image may
+	// declare for it to be decoded at all, as a few bytes of PNG or JPEG
+	// can claim enough to exhaust memory. Zero means DefaultMaxPixels, and
+	// a negative number doesn't limit.
+	MaxPixels int
+}
+
+// DefaultMaxPixels is 64 megapixels, more than any phone camera takes.
+const DefaultMaxPixels = 64 << 20
+
+// DefaultPhotoLimits suit the clients that choke on big photos: a 512
+// pixel square is plenty for a contact, and 100KB keeps cards mailable.
+var DefaultPhotoLimits = PhotoLimits{MaxDimension: 512, MaxBytes: 100 * 1024}
+
+// minJPEGQuality is the worst quality ShrinkMedia will use before scaling
+// an image down further instead.
+const minJPEGQuality = 40
+
+// ShrinkMedia fits inline images within limits, scaling them down to
+// MaxDimension and re-encoding them until they fit MaxBytes. PNGs and GIFs
+// become PNGs, or JPEGs if that's the only way to fit; JPEGs stay JPEGs,
+// turned upright by their EXIF orientation, as the rest of their EXIF
+// metadata is lost. Images already within limits, external media, and
+// anything the image package can't decode are returned as they are, but
+// images with more than MaxPixels fail with ErrTooManyPixels.
+func ShrinkMedia(m Media, limits PhotoLimits) (Media, error) {
+	if !m.Inline() || !strings.HasPrefix(m.MediaType, "image/") {
+		return m, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(m.Data))
+	if err != nil {
+		return m, nil
+	}
+	maxPixels := int64(limits.MaxPixels)
+	if maxPixels == 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	if maxPixels > 0 && int64(config.Width)*int64(config.Height) > maxPixels {
+		return m, ErrTooManyPixels
+	}
+	img, format, err := image.Decode(bytes.NewReader(m.Data))
+	if err != nil {
+		return m, nil
+	}
+	bounds := img.Bounds()
+	tooWide := limits.MaxDimension > 0 && (bounds.Dx() > limits.MaxDimension || bounds.Dy() > limits.MaxDimension)
+	tooHeavy := limits.MaxBytes > 0 && len(m.Data) > limits.MaxBytes
+	if !tooWide && !tooHeavy {
+		return m, nil
+	}
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(m.Data))
+	}
+	if tooWide {
+		img = scaleDown(img, limits.MaxDimension)
+	}
+	for {
+		if format != "jpeg" {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return m, err
+			}
+			if fits(buf.Len(), limits) {
+				return Media{MediaType: "image/png", Data: buf.Bytes()}, nil
+			}
+		}
+		for quality := 85; quality >= minJPEGQuality; quality -= 15 {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality}); err != nil {
+				return m, err
+			}
+			if fits(buf.Len(), limits) {
+				return Media{MediaType: "image/jpeg", Data: buf.Bytes()}, nil
+			}
+		}
+		// Still too heavy even at low quality, so lose some pixels.
+		bounds = img.Bounds()
+		if bounds.Dx() < 16 && bounds.Dy() < 16 {
+			return m, ErrPhotoTooLarge
+		}
+		img = scaleDown(img, maxInt(bounds.Dx(), bounds.Dy())*3/4)
+	}
+}
+
+func fits(size int, limits PhotoLimits) bool {
+	return limits.MaxBytes <= 0 || size <= limits.MaxBytes
+}
+
+// scaleDown shrinks img so that neither side exceeds limit pixels, keeping
+// its aspect ratio, by averaging the source pixels under each new pixel.
+// There's no scaler in the standard library, and a box filter is as good
+// as any for making things smaller.
+func scaleDown(img image.Image, limit int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= limit && h <= limit {
+		return img
+	}
+	nw, nh := limit, h*limit/w
+	if h > w {
+		nw, nh = w*limit/h, limit
+	}
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+	scaled := image.NewNRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0, y1 := bounds.Min.Y+y*h/nh, bounds.Min.Y+(y+1)*h/nh
+		for x := 0; x < nw; x++ {
+			x0, x1 := bounds.Min.X+x*w/nw, bounds.Min.X+(x+1)*w/nw
+			var r, g, b, a, n uint64
+			for sy := y0; sy < maxInt(y1, y0+1); sy++ {
+				for sx := x0; sx < maxInt(x1, x0+1); sx++ {
+					c := color.NRGBA64Model.Convert(img.At(sx, sy)).(color.NRGBA64)
+					r, g, b, a, n = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), a+uint64(c.A), n+1
+				}
+			}
+			scaled.Set(x, y, color.NRGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return scaled
+}
+
+// flatten draws img over white, as JPEG has no transparency.
+func flatten(img image.Image) image.Image {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flat
+}
+
+// shrinkPhotoDatum applies ShrinkMedia to a PHOTO or LOGO datum, keeping
+// it in the form it was found in.
+func shrinkPhotoDatum(d VcardDatum, limits PhotoLimits) (VcardDatum, error) {
+	m, err := d.Media()
+	if err != nil || !m.Inline() {
+		return d, nil
+	}
+	shrunk, err := ShrinkMedia(m, limits)
+	if err != nil || bytes.Equal(shrunk.Data, m.Data) {
+		return d, err
+	}
+	version := "4.0"
+	if d.ValueType == BinaryValueType || isBase64Encoding(d.Attrs.first("ENCODING")) {
+		version = "3.0"
+	}
+	shrunkDatum := MediaDatum(d.FieldName, shrunk, d.Attrs, version)
+	shrunkDatum.Spelling = d.Spelling
+	return shrunkDatum, nil
+}
+
+// ShrinkPhotos returns a copy of the card with its embedded PHOTO and LOGO
+// images fitted within limits by ShrinkMedia.
+func ShrinkPhotos(v Vcard, limits PhotoLimits) (Vcard, error) {
+	shrunk := v.withData(make([]VcardDatum, len(v.Data)))
+	for i, d := range v.Data {
+		if field := strings.ToUpper(d.FieldName); field == "PHOTO" || field == "LOGO" {
+			var err error
+			if d, err = shrinkPhotoDatum(d, limits); err != nil {
+				return Vcard{}, PropertyError{field, err}
+			}
+		}
+		shrunk.Data[i] = d
+	}
+	return shrunk, nil
+}
+
+// ShrinkPhotoEncoder returns a DatumEncoder for the SpecialRules of
+// PHOTO and LOGO that shrinks embedded images as they're written.
+func ShrinkPhotoEncoder(limits PhotoLimits) DatumEncoder {
+	return func(d VcardDatum) (string, error) {
+		d, err := shrinkPhotoDatum(d, limits)
+		if err != nil {
+			return "", err
+		}
+		return d.Output(nil)
+	}
+}
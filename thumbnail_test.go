@@ -0,0 +1,153 @@
+package vcardenc
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// noisyImage is incompressible enough to blow a byte budget.
+func noisyImage(w, h int) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	rng := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(rng.Intn(256)), uint8(x), uint8(y), 255})
+		}
+	}
+	return img
+}
+
+func encodedImage(t *testing.T, img image.Image, format string) []byte {
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	}
+	assert.Nil(t, err)
+	return buf.Bytes()
+}
+
+func TestShrinkMedia(t *testing.T) {
+	limits := PhotoLimits{MaxDimension: 100, MaxBytes: 8 * 1024}
+	for _, format := range []string{"jpeg", "png"} {
+		original := Media{MediaType: "image/" + format, Data: encodedImage(t, noisyImage(400, 200), format)}
+		shrunk, err := ShrinkMedia(original, limits)
+		assert.Nil(t, err, format)
+		assert.True(t, len(shrunk.Data) <= limits.MaxBytes, format)
+		img, _, err := image.Decode(bytes.NewReader(shrunk.Data))
+		assert.Nil(t, err, format)
+		assert.True(t, img.Bounds().Dx() <= 100 && img.Bounds().Dy() <= 50, format)
+	}
+
+	small := Media{MediaType: "image/png", Data: encodedImage(t, noisyImage(10, 10), "png")}
+	same, err := ShrinkMedia(small, limits)
+	assert.Nil(t, err)
+	assert.Equal(t, small, same)
+
+	external := Media{MediaType: "image/jpeg", URI: "https://example.com/huge.jpg"}
+	same, err = ShrinkMedia(external, limits)
+	assert.Nil(t, err)
+	assert.Equal(t, external, same)
+
+	jpg := Media{MediaType: "image/jpeg", Data: encodedImage(t, noisyImage(64, 64), "jpeg")}
+	_, err = ShrinkMedia(jpg, PhotoLimits{MaxBytes: 10})
+	assert.Equal(t, ErrPhotoTooLarge, err)
+}
+
+func TestShrinkPhotos(t *testing.T) {
+	big := encodedImage(t, noisyImage(600, 600), "jpeg")
+	card := Vcard{Version: "3.0", Data: []VcardDatum{
+		StringDatum("FN", nil, "Joe Bloggs"),
+		MediaDatum("PHOTO", Media{MediaType: "image/jpeg", Data: big}, AttrMap{"TYPE": []string{"work"}}, "3.0"),
+		StringDatum("LOGO", nil, "https://example.com/logo.png"),
+	}}
+	shrunk, err := ShrinkPhotos(card, DefaultPhotoLimits)
+	assert.Nil(t, err)
+	assert.Equal(t, card.Data[0], shrunk.Data[0])
+	assert.Equal(t, card.Data[2], shrunk.Data[2])
+	assert.Equal(t, BinaryValueType, shrunk.Data[1].ValueType)
+	assert.True(t, shrunk.Data[1].HasType("work"))
+	assert.True(t, len(shrunk.Data[1].BinaryValue) < len(big))
+
+	encoded, err := card.Encode(map[string]DatumEncoder{"PHOTO": ShrinkPhotoEncoder(DefaultPhotoLimits)})
+	assert.Nil(t, err)
+	expected, err := shrunk.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, encoded)
+}
+
+// exifJPEG encodes img as a JPEG carrying an EXIF orientation.
+func exifJPEG(t *testing.T, img image.Image, orientation byte) []byte {
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, 0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xFF, 0xE1, 0, byte(len(payload) + 2)}, payload...)
+	data := encodedImage(t, img, "jpeg")
+	return append(append(data[:2:2], app1...), data[2:]...)
+}
+
+func TestShrinkMediaOrientation(t *testing.T) {
+	// Red on the left and blue on the right, as a phone held on its left
+	// side would store a photo with red at the top.
+	sideways := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.NRGBA{255, 0, 0, 255}
+			if x >= 20 {
+				c = color.NRGBA{0, 0, 255, 255}
+			}
+			sideways.Set(x, y, c)
+		}
+	}
+	data := exifJPEG(t, sideways, 6)
+	assert.Equal(t, 6, jpegOrientation(data))
+	assert.Equal(t, 1, jpegOrientation(encodedImage(t, sideways, "jpeg")))
+
+	shrunk, err := ShrinkMedia(Media{MediaType: "image/jpeg", Data: data}, PhotoLimits{MaxDimension: 10})
+	assert.Nil(t, err)
+	img, _, err := image.Decode(bytes.NewReader(shrunk.Data))
+	assert.Nil(t, err)
+	assert.Equal(t, image.Rect(0, 0, 5, 10), img.Bounds())
+	r, _, b, _ := img.At(2, 1).RGBA()
+	assert.True(t, r > b, "red at the top")
+	r, _, b, _ = img.At(2, 8).RGBA()
+	assert.True(t, b > r, "blue at the bottom")
+}
+
+func TestOrient(t *testing.T) {
+	// Where the top left pixel of a 3 by 2 image goes.
+	corners := map[int]image.Point{
+		1: {0, 0}, 2: {2, 0}, 3: {2, 1}, 4: {0, 1},
+		5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2},
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.White)
+	for orientation, corner := range corners {
+		oriented := orient(img, orientation)
+		assert.Equal(t, color.NRGBAModel.Convert(color.White), color.NRGBAModel.Convert(oriented.At(corner.X, corner.Y)), orientation)
+		if orientation >= 5 {
+			assert.Equal(t, image.Rect(0, 0, 2, 3), oriented.Bounds(), orientation)
+		}
+	}
+}
+
+func TestShrinkMediaPixelLimit(t *testing.T) {
+	// A GIF claiming to be 65535 pixels square, in a dozen bytes.
+	bomb := Media{MediaType: "image/gif", Data: []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00;")}
+	_, err := ShrinkMedia(bomb, DefaultPhotoLimits)
+	assert.Equal(t, ErrTooManyPixels, err)
+
+	img := Media{MediaType: "image/png", Data: encodedImage(t, noisyImage(100, 100), "png")}
+	_, err = ShrinkMedia(img, PhotoLimits{MaxDimension: 50, MaxPixels: 5000})
+	assert.Equal(t, ErrTooManyPixels, err)
+	_, err = ShrinkMedia(img, PhotoLimits{MaxDimension: 50, MaxPixels: -1})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,54 @@
+package vcardenc
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrBadTimestamp is returned when a timestamp value, like that of REV or
+// CREATED, isn't one.
+var ErrBadTimestamp = errors.New("Malformed timestamp")
+
+// timestampLayouts are the forms of RFC 6350 section 4.3.5, then the
+// extended ISO 8601 forms that 3.0 exporters still write.
+var timestampLayouts = []string{
+	"20060102T150405Z",
+	"20060102T150405-07",
+	"20060102T150405-0700",
+	"20060102T150405",
+	"2006-01-02T15:04:05Z",
+	"2006-01-02T15:04:05-07:00",
+	"2006-01-02T15:04:05",
+}
+
+// ParseTimestamp reads a timestamp like "19961022T140000Z". Timestamps
+// without a UTC offset are taken to be UTC, as there's nothing better to do.
+func ParseTimestamp(value string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrBadTimestamp
+}
+
+// FormatTimestamp writes t in UTC as a basic format timestamp, the form
+// RFC 6350 asks for.
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// Timestamp parses the value of a REV or CREATED datum with ParseTimestamp.
+func (datum VcardDatum) Timestamp() (time.Time, error) {
+	return ParseTimestamp(datum.StringValue)
+}
+
+// TimestampDatum makes a datum, like REV or CREATED, holding t.
+func TimestampDatum(fieldName string, attrs AttrMap, t time.Time) VcardDatum {
+	return StringDatum(fieldName, attrs, FormatTimestamp(t))
+}
+
+func validateTimestamp(datum VcardDatum) error {
+	_, err := datum.Timestamp()
+	return err
+}
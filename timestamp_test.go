@@ -0,0 +1,28 @@
+package vcardenc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var timestampTCs = map[string]time.Time{
+	"19961022T140000Z":          time.Date(1996, 10, 22, 14, 0, 0, 0, time.UTC),
+	"19961022T140000":           time.Date(1996, 10, 22, 14, 0, 0, 0, time.UTC),
+	"19961022T090000-05":        time.Date(1996, 10, 22, 14, 0, 0, 0, time.UTC),
+	"19961022T093000-0430":      time.Date(1996, 10, 22, 14, 0, 0, 0, time.UTC),
+	"1996-10-22T14:00:00Z":      time.Date(1996, 10, 22, 14, 0, 0, 0, time.UTC),
+	"1996-10-22T16:00:00+02:00": time.Date(1996, 10, 22, 14, 0, 0, 0, time.UTC),
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for s, expected := range timestampTCs {
+		ts, err := ParseTimestamp(s)
+		assert.Nil(t, err, s)
+		assert.True(t, expected.Equal(ts), s)
+		assert.Equal(t, "19961022T140000Z", FormatTimestamp(ts))
+	}
+	_, err := ParseTimestamp("19961022")
+	assert.Equal(t, ErrBadTimestamp, err)
+}
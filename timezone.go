@@ -0,0 +1,175 @@
+package vcardenc
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrBadUTCOffset is returned when a utc-offset TZ value is malformed.
+	ErrBadUTCOffset = errors.New("Malformed UTC offset")
+
+	// ErrUnknownTimeZone is returned when a TZ can't be found in the tz
+	// database, or is a URI, which can mean anything at all.
+	ErrUnknownTimeZone = errors.New("Unknown time zone")
+)
+
+// TimeZone is a TZ value, which RFC 6350 allows to be any of a time zone
+// name, a UTC offset or a URI. Exactly one of Name, HasOffset and URI is set.
+type TimeZone struct {
+	// Name is a name like "America/New_York", ideally from the tz database.
+	Name string
+	// Offset is the offset from UTC in seconds, if HasOffset.
+	Offset    int
+	HasOffset bool
+	// URI is a URI naming the time zone.
+	URI string
+}
+
+// ParseUTCOffset reads a UTC offset like "-0500", "+05:30" or "-05",
+// returning it in seconds.
+func ParseUTCOffset(s string) (int, error) {
+	if len(s) < 3 || (s[0] != '+' && s[0] != '-') {
+		return 0, ErrBadUTCOffset
+	}
+	hhmm := s[1:]
+	if len(hhmm) == 5 && hhmm[2] == ':' {
+		hhmm = hhmm[:2] + hhmm[3:]
+	}
+	if len(hhmm) == 2 {
+		hhmm += "00"
+	}
+	if len(hhmm) != 4 {
+		return 0, ErrBadUTCOffset
+	}
+	for _, c := range hhmm {
+		if c < '0' || c > '9' {
+			return 0, ErrBadUTCOffset
+		}
+	}
+	hours := int(hhmm[0]-'0')*10 + int(hhmm[1]-'0')
+	minutes := int(hhmm[2]-'0')*10 + int(hhmm[3]-'0')
+	if hours > 14 || minutes > 59 {
+		return 0, ErrBadUTCOffset
+	}
+	offset := hours*3600 + minutes*60
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// formatUTCOffset writes an offset in seconds as "-0500", or with extended
+// true as vCard 3.0 does, "-05:00".
+func formatUTCOffset(offset int, extended bool) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	hhmm := []string{twoDigits(offset / 3600), twoDigits(offset % 3600 / 60)}
+	if extended {
+		return sign + strings.Join(hhmm, ":")
+	}
+	return sign + strings.Join(hhmm, "")
+}
+
+func twoDigits(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// TimeZone reads the value of a TZ datum, going by its VALUE parameter or,
+// without one, taking anything that looks like a UTC offset as one, as
+// vCard 3.0 defaults to offsets and 4.0 to text.
+func (datum VcardDatum) TimeZone() (TimeZone, error) {
+	value := strings.TrimSpace(datum.StringValue)
+	switch strings.ToLower(datum.Attrs.first("VALUE")) {
+	case "uri":
+		return TimeZone{URI: value}, nil
+	case "text":
+		return TimeZone{Name: value}, nil
+	case "utc-offset":
+		offset, err := ParseUTCOffset(value)
+		if err != nil {
+			return TimeZone{}, err
+		}
+		return TimeZone{Offset: offset, HasOffset: true}, nil
+	}
+	if offset, err := ParseUTCOffset(value); err == nil {
+		return TimeZone{Offset: offset, HasOffset: true}, nil
+	}
+	return TimeZone{Name: value}, nil
+}
+
+// Location resolves the time zone: names through the tz database with
+// time.LoadLocation, and offsets as fixed zones. URIs can't be resolved.
+func (tz TimeZone) Location() (*time.Location, error) {
+	switch {
+	case tz.HasOffset:
+		return time.FixedZone(formatUTCOffset(tz.Offset, true), tz.Offset), nil
+	case tz.Name != "":
+		loc, err := time.LoadLocation(tz.Name)
+		if err != nil {
+			return nil, ErrUnknownTimeZone
+		}
+		return loc, nil
+	}
+	return nil, ErrUnknownTimeZone
+}
+
+// TimeZoneDatum makes a TZ datum in the form version expects: for "4.0",
+// names as plain text and offsets with VALUE=utc-offset, and otherwise
+// offsets as plain "-05:00" and names with VALUE=text. attrs is copied,
+// not changed.
+func TimeZoneDatum(tz TimeZone, attrs AttrMap, version string) VcardDatum {
+	d := StringDatum("TZ", copyAttrs(attrs), tz.Name)
+	d.setParam("VALUE")
+	switch {
+	case tz.URI != "":
+		d.StringValue = tz.URI
+		d.setParam("VALUE", "uri")
+	case tz.HasOffset && version == "4.0":
+		d.StringValue = formatUTCOffset(tz.Offset, false)
+		d.setParam("VALUE", "utc-offset")
+	case tz.HasOffset:
+		d.StringValue = formatUTCOffset(tz.Offset, true)
+	case version != "4.0":
+		d.setParam("VALUE", "text")
+	}
+	return d
+}
+
+// convertGeoTZ rewrites GEO and TZ values in the form version expects, as
+// GeoDatum and TimeZoneDatum make them, leaving anything it can't parse
+// alone. Writing a geo: URI as 3.0 loses its altitude and uncertainty.
+func convertGeoTZ(d *VcardDatum, version string) {
+	converted := *d
+	modern := version == "4.0"
+	switch strings.ToUpper(d.FieldName) {
+	case "GEO":
+		isURI := strings.HasPrefix(strings.ToLower(strings.TrimSpace(d.StringValue)), "geo:")
+		if g, err := d.Geo(); err == nil && isURI != modern {
+			converted = GeoDatum(g, d.Attrs, version)
+		}
+	case "TZ":
+		tz, err := d.TimeZone()
+		if err != nil || tz.URI != "" {
+			break
+		}
+		value := d.Attrs.first("VALUE")
+		switch {
+		// 3.0 offsets have no VALUE, and 4.0 ones VALUE=utc-offset.
+		case tz.HasOffset && modern && value == "",
+			tz.HasOffset && !modern && strings.EqualFold(value, "utc-offset"),
+			// Names are 4.0's default, but need VALUE=text in 3.0.
+			!tz.HasOffset && !modern && value == "":
+			converted = TimeZoneDatum(tz, d.Attrs, version)
+		}
+	}
+	converted.FieldName, converted.Spelling = d.FieldName, d.Spelling
+	*d = converted
+}
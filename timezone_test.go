@@ -0,0 +1,81 @@
+package vcardenc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	timeZoneTCs = map[string]TimeZone{
+		"TZ:-05:00":                 {Offset: -5 * 3600, HasOffset: true},
+		"TZ;VALUE=utc-offset:+0530": {Offset: 5*3600 + 30*60, HasOffset: true},
+		"TZ:Raleigh/North America":  {Name: "Raleigh/North America"},
+		"TZ;VALUE=text:-05:00":      {Name: "-05:00"},
+		"TZ:Europe/Dublin":          {Name: "Europe/Dublin"},
+		"TZ;VALUE=uri:https://example.com/tz-database/acdt": {URI: "https://example.com/tz-database/acdt"},
+	}
+	badUTCOffsets = []string{"", "0500", "+5", "-05:0", "+2500", "-05:60", "+ab:cd", "+-100", "-+1", "+00-1", "+0:530", "+05:3:"}
+)
+
+func TestTimeZone(t *testing.T) {
+	for line, expected := range timeZoneTCs {
+		d, err := ParseDatumLine(line)
+		assert.Nil(t, err, line)
+		tz, err := d.TimeZone()
+		assert.Nil(t, err, line)
+		assert.Equal(t, expected, tz, line)
+	}
+	for _, s := range badUTCOffsets {
+		_, err := ParseUTCOffset(s)
+		assert.Equal(t, ErrBadUTCOffset, err, s)
+	}
+}
+
+func TestTimeZoneLocation(t *testing.T) {
+	loc, err := TimeZone{Offset: -5 * 3600, HasOffset: true}.Location()
+	assert.Nil(t, err)
+	assert.Equal(t, "-05:00", loc.String())
+
+	_, err = TimeZone{Name: "Raleigh/North America"}.Location()
+	assert.Equal(t, ErrUnknownTimeZone, err)
+	_, err = TimeZone{URI: "https://example.com/tz"}.Location()
+	assert.Equal(t, ErrUnknownTimeZone, err)
+}
+
+func TestTimeZoneDatum(t *testing.T) {
+	offset := TimeZone{Offset: -5 * 3600, HasOffset: true}
+	out, _ := TimeZoneDatum(offset, nil, "4.0").Output(nil)
+	assert.Equal(t, "TZ;VALUE=utc-offset:-0500\n", out)
+	out, _ = TimeZoneDatum(offset, nil, "3.0").Output(nil)
+	assert.Equal(t, "TZ:-05:00\n", out)
+	out, _ = TimeZoneDatum(TimeZone{Name: "Europe/Dublin"}, nil, "3.0").Output(nil)
+	assert.Equal(t, "TZ;VALUE=text:Europe/Dublin\n", out)
+}
+
+func TestEncodeConvertsGeoTZ(t *testing.T) {
+	card, err := ParseVcard("BEGIN:VCARD\nVERSION:3.0\nFN:Joe\nGEO:37.386013;-122.082932\ntz:-05:00\nEND:VCARD", nil)
+	assert.Nil(t, err)
+	out, err := card.EncodeWith(EncodeOptions{PreserveCase: true})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCARD\nVERSION:3.0\n"), out)
+	assert.True(t, strings.Contains(out, "\nGEO:37.386013;-122.082932\n"), out)
+
+	card.Version = "4.0"
+	out, err = card.EncodeWith(EncodeOptions{PreserveCase: true})
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(out, "\nGEO:geo:37.386013,-122.082932\n"), out)
+	assert.True(t, strings.Contains(out, "\ntz;VALUE=utc-offset:-0500\n"), out)
+
+	// And back down again.
+	card, err = ParseVcard(out, nil)
+	assert.Nil(t, err)
+	card.Data = append(card.Data, StringDatum("TZ", nil, "Europe/Dublin"))
+	card.Data = append(card.Data, StringDatum("GEO", nil, "geo:48.2010,16.3695,183;u=40"))
+	card.Version = "3.0"
+	out, err = card.EncodeWith(EncodeOptions{PreserveCase: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:3.0\nFN:Joe\nGEO:37.386013;-122.082932\ntz:-05:00\n"+
+		"TZ;VALUE=text:Europe/Dublin\nGEO:48.201;16.3695\nEND:VCARD", out)
+}
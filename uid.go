@@ -0,0 +1,128 @@
+package vcardenc
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrDuplicateUID is returned by ValidateUIDs when cards share a UID.
+var ErrDuplicateUID = errors.New("UID shared by several cards")
+
+// DuplicateUIDError ties ErrDuplicateUID to the UID and the indices of
+// the cards sharing it.
+type DuplicateUIDError struct {
+	UID   string
+	Cards []int
+}
+
+func (e DuplicateUIDError) Error() string {
+	cards := make([]string, len(e.Cards))
+	for i, c := range e.Cards {
+		cards[i] = strconv.Itoa(c)
+	}
+	return e.UID + ": " + ErrDuplicateUID.Error() + " (" + strings.Join(cards, ", ") + ")"
+}
+
+// Unwrap returns ErrDuplicateUID, so errors.Is works on DuplicateUIDErrors.
+func (e DuplicateUIDError) Unwrap() error {
+	return ErrDuplicateUID
+}
+
+// uidNamespace is the RFC 4122 name space DeterministicUID hashes card
+// fingerprints in. It was made up for the purpose; don't change it, or
+// every derived UID changes with it.
+var uidNamespace = [16]byte{
+	0x6b, 0x1d, 0x3e, 0x52, 0x0c, 0x9f, 0x4a, 0x8e,
+	0x9d, 0x27, 0x5e, 0x40, 0xb1, 0x7c, 0x63, 0xf4,
+}
+
+// NewUID returns a random (version 4) UUID as a urn:uuid: URI, the form of
+// UID that RFC 6350 recommends.
+func NewUID() (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+	return formatUUID(uuid, 4), nil
+}
+
+// DeterministicUID derives a name-based (version 5) UUID from the card's
+// Fingerprint, ignoring any UID and VolatileProperties, so that importing
+// the same card twice gives it the same UID.
+func DeterministicUID(v Vcard) (string, error) {
+	fp, err := Fingerprint(v, FingerprintOptions{ExcludeVolatile: true, Exclude: []string{"UID"}})
+	if err != nil {
+		return "", err
+	}
+	hash := sha1.New()
+	hash.Write(uidNamespace[:])
+	hash.Write([]byte(fp))
+	var uuid [16]byte
+	copy(uuid[:], hash.Sum(nil))
+	return formatUUID(uuid, 5), nil
+}
+
+// formatUUID sets the version and RFC 4122 variant bits of uuid and
+// formats it as a urn:uuid: URI.
+func formatUUID(uuid [16]byte, version byte) string {
+	uuid[6] = uuid[6]&0x0f | version<<4
+	uuid[8] = uuid[8]&0x3f | 0x80
+	h := hex.EncodeToString(uuid[:])
+	return "urn:uuid:" + h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// EnsureUID returns the card with a UID added if it has none: derived from
+// its content by DeterministicUID if deterministic is set, for idempotent
+// imports, or otherwise random. Cards with a UID are returned as they are.
+func EnsureUID(v Vcard, deterministic bool) (Vcard, error) {
+	if _, ok := v.Get("UID"); ok {
+		return v, nil
+	}
+	var (
+		uid string
+		err error
+	)
+	if deterministic {
+		uid, err = DeterministicUID(v)
+	} else {
+		uid, err = NewUID()
+	}
+	if err != nil {
+		return Vcard{}, err
+	}
+	withUID := v.withData(make([]VcardDatum, 0, len(v.Data)+1))
+	withUID.Data = append(append(withUID.Data, v.Data...), StringDatum("UID", nil, uid))
+	return withUID, nil
+}
+
+// ValidateUIDs checks that no two cards share a UID, comparing urn:uuid:
+// UIDs and bare UUIDs ignoring case, and returns a DuplicateUIDError for
+// each UID that's shared, in order of first appearance.
+func ValidateUIDs(cards []Vcard) (errs []error) {
+	var (
+		order []string
+		seen  = make(map[string][]int)
+	)
+	for i, v := range cards {
+		uid, ok := v.Get("UID")
+		if !ok {
+			continue
+		}
+		key := uidKey(uid.StringValue)
+		if _, ok := seen[key]; !ok {
+			order = append(order, key)
+		}
+		seen[key] = append(seen[key], i)
+	}
+	for _, key := range order {
+		if indices := seen[key]; len(indices) > 1 {
+			uid, _ := cards[indices[0]].Get("UID")
+			errs = append(errs, DuplicateUIDError{uid.StringValue, indices})
+		}
+	}
+	return errs
+}
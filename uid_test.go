@@ -0,0 +1,66 @@
+package vcardenc
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidURN = regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-([45])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUID(t *testing.T) {
+	a, err := NewUID()
+	assert.Nil(t, err)
+	b, err := NewUID()
+	assert.Nil(t, err)
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, "4", uuidURN.FindStringSubmatch(a)[1])
+}
+
+func TestEnsureUID(t *testing.T) {
+	card := Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Joe Bloggs"),
+		StringDatum("REV", nil, "20200101T000000Z"),
+	}}
+	a, err := EnsureUID(card, true)
+	assert.Nil(t, err)
+	assert.Len(t, card.Data, 2, "the original is untouched")
+	uid, ok := a.Get("UID")
+	assert.True(t, ok)
+	assert.Equal(t, "5", uuidURN.FindStringSubmatch(uid.StringValue)[1])
+
+	// Volatile properties and name casing don't change the derived UID.
+	card.Data[1].StringValue = "20240101T000000Z"
+	card.Data[0].FieldName = "fn"
+	b, err := EnsureUID(card, true)
+	assert.Nil(t, err)
+	assert.Equal(t, a.Data[2], b.Data[2])
+
+	same, err := EnsureUID(a, false)
+	assert.Nil(t, err)
+	assert.Equal(t, a, same)
+
+	random, err := EnsureUID(card, false)
+	assert.Nil(t, err)
+	assert.NotEqual(t, a.Data[2], random.Data[2])
+}
+
+func TestValidateUIDs(t *testing.T) {
+	card := func(uid string) Vcard {
+		return Vcard{Data: []VcardDatum{StringDatum("UID", nil, uid)}}
+	}
+	cards := []Vcard{
+		card("urn:uuid:03a0e51f-d1aa-4385-8a08-d91e8e2c9b1a"),
+		card("https://example.com/joe"),
+		{},
+		card("03A0E51F-D1AA-4385-8A08-D91E8E2C9B1A"),
+		card("https://example.com/jane"),
+		{},
+	}
+	errs := ValidateUIDs(cards)
+	assert.Equal(t, []error{DuplicateUIDError{"urn:uuid:03a0e51f-d1aa-4385-8a08-d91e8e2c9b1a", []int{0, 3}}}, errs)
+	assert.True(t, errors.Is(errs[0], ErrDuplicateUID))
+	assert.Empty(t, ValidateUIDs(cards[1:3]))
+}
@@ -1,5 +1,7 @@
 package vcardenc
 
+import "strings"
+
 // finds the end of a quoted string, assuming the opening quotation mark is
 // stripped from line.
 func parseQuotedValue(line string, delimCs []rune, expectClosing bool) (parsedLine, remaining string, err error) {
@@ -12,15 +14,15 @@ func parseQuotedValue(line string, delimCs []rune, expectClosing bool) (parsedLi
 			escaped = true
 			continue
 		}
-		if runeSliceContains(delimCs, c) {
-			if escaped {
-				if c == 'n' {
-					c = '\n'
-				}
-				parsedChars = append(parsedChars, c)
-				escaped = false
-				continue
+		if escaped {
+			if c == 'n' || c == 'N' {
+				c = '\n'
 			}
+			parsedChars = append(parsedChars, c)
+			escaped = false
+			continue
+		}
+		if runeSliceContains(delimCs, c) {
 			parsedLine = line[:n]
 			if expectClosing {
 				remaining = line[n+1:]
@@ -38,6 +40,30 @@ func parseQuotedValue(line string, delimCs []rune, expectClosing bool) (parsedLi
 	return "", "", ErrFailedToParseQuotedString
 }
 
+// splitUnescaped splits s on every delimiter that isn't backslash-escaped,
+// leaving escape sequences in the pieces untouched so they can be split again.
+func splitUnescaped(s string, delimiter rune) (pieces []string) {
+	var (
+		escaped bool
+		start   int
+	)
+	for n, c := range s {
+		if escaped {
+			escaped = false
+			continue
+		}
+		if c == '\\' {
+			escaped = true
+			continue
+		}
+		if c == delimiter {
+			pieces = append(pieces, s[start:n])
+			start = n + 1
+		}
+	}
+	return append(pieces, s[start:])
+}
+
 func runeSliceContains(slice []rune, R rune) bool {
 	for _, r := range slice {
 		if r == R {
@@ -55,3 +81,75 @@ func stringSliceContains(slice []string, S string) bool {
 	}
 	return false
 }
+
+// Similarity is one minus the edit distance of x and y over the length of
+// the longer, so identical strings are 1 and entirely different ones near 0.
+func Similarity(x, y string) float64 {
+	xr, yr := []rune(x), []rune(y)
+	longest := len(xr)
+	if len(yr) > longest {
+		longest = len(yr)
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(xr, yr))/float64(longest)
+}
+
+func levenshtein(x, y []rune) int {
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		cur[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func stringSliceContainsFold(slice []string, S string) bool {
+	for _, s := range slice {
+		if strings.EqualFold(s, S) {
+			return true
+		}
+	}
+	return false
+}
+
+// isToken reports whether s is an iana-token of RFC 6350, one or more
+// letters, digits and dashes, as enumerated values that may be extended
+// are. This includes x-names.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -2,30 +2,61 @@
  */
 package vcardenc
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 // Vcard contains data that can be encoded.
 // A Vcard begins with a "Begin:vCard" datum and ends similarly,
 // and some standards require an immediate version datum. In this
 // implementation, if those entries are present as data they'll be
-// ignored, and the vCard 4 version will be used even if the results
-// are completely inconsistent and terrible. Sorry.
+// ignored, and Version is written instead, or if it's empty the value
+// of a VERSION datum, or failing that 4.0, even if the results are
+// completely inconsistent and terrible. Sorry.
 // Because vCard is awful and IDGAF this makes no guarantees of
 // output validity, and data beyond the most basic behaviour will
 // probably fail to encode properly. Don't blame me, blame vCard.
 type Vcard struct {
-	Data []VcardDatum
+	// Version is the VERSION a parsed card declared, and the one Encode
+	// writes. If empty, Encode writes any VERSION datum in Data, or 4.0.
+	Version string `json:"version,omitempty"`
+
+	// Kind, Gender and GramGender are the card's KIND, GENDER and first
+	// GRAMGENDER, read from Data when a card is parsed. Set, Encode writes
+	// them in place of those data, keeping their parameters; empty, it
+	// writes Data as it is. SyncFields and SyncData bring the two into line
+	// at other times.
+	Kind       Kind       `json:"kind,omitempty"`
+	Gender     *Gender    `json:"gender,omitempty"`
+	GramGender GramGender `json:"gramGender,omitempty"`
+
+	Data []VcardDatum `json:"data"`
 }
 
 // Encode returns something that might parse as a vCard in client software.
 func (v Vcard) Encode(specialRules map[string]DatumEncoder) (string, error) {
-	var output = "BEGIN:VCARD\nVERSION:4.0\n"
+	return v.EncodeWith(EncodeOptions{SpecialRules: specialRules})
+}
+
+// EncodeWith is Encode, configured by opts. GEO, TZ and inline PHOTO, LOGO,
+// SOUND and KEY values are written in the forms of the card's version.
+func (v Vcard) EncodeWith(opts EncodeOptions) (string, error) {
+	v, err := v.SyncData().stamp(opts)
+	if err != nil {
+		return "", err
+	}
+	version := v.encodingVersion()
+	var output = "BEGIN:VCARD\nVERSION:" + version + "\n"
 	for _, d := range v.Data {
 		field := strings.ToUpper(d.FieldName)
 		if field == "BEGIN" || field == "VERSION" || field == "END" {
 			continue
 		}
-		dout, err := d.Output(specialRules)
+		// Values had better be written in the forms of the version.
+		convertGeoTZ(&d, version)
+		convertMedia(&d, version)
+		dout, err := d.OutputWith(opts)
 		if err != nil {
 			return "", err
 		}
@@ -33,3 +64,164 @@ func (v Vcard) Encode(specialRules map[string]DatumEncoder) (string, error) {
 	}
 	return output + "END:VCARD", nil
 }
+
+// indexOfField is the index of the first datum named fieldName, ignoring
+// case, or -1.
+func indexOfField(data []VcardDatum, fieldName string) int {
+	for i, d := range data {
+		if strings.EqualFold(d.FieldName, fieldName) {
+			return i
+		}
+	}
+	return -1
+}
+
+// encodingVersion is the version Encode writes the card as.
+func (v Vcard) encodingVersion() string {
+	if v.Version != "" {
+		return v.Version
+	}
+	if d, ok := v.Get("VERSION"); ok && strings.TrimSpace(d.StringValue) != "" {
+		return strings.TrimSpace(d.StringValue)
+	}
+	return "4.0"
+}
+
+// withData returns a copy of the card with data in place of its own.
+func (v Vcard) withData(data []VcardDatum) Vcard {
+	v.Data = data
+	return v
+}
+
+// SyncFields returns a copy of the card with Kind, Gender and GramGender
+// read from Data, left empty where it has no such datum or it's malformed.
+func (v Vcard) SyncFields() Vcard {
+	v.Kind, v.Gender, v.GramGender = "", nil, ""
+	if d, ok := v.Get("KIND"); ok {
+		v.Kind, _ = ParseKind(d.StringValue)
+	}
+	if d, ok := v.Get("GENDER"); ok {
+		if g, err := d.Gender(); err == nil {
+			v.Gender = &g
+		}
+	}
+	if d, ok := v.Get("GRAMGENDER"); ok {
+		v.GramGender, _ = ParseGramGender(d.StringValue)
+	}
+	return v
+}
+
+// syncedField is a typed field of a Vcard as SyncData writes it.
+type syncedField struct {
+	datum VcardDatum
+	// same reports whether a datum already holds the field's value.
+	same func(VcardDatum) bool
+	// single is set for properties that may appear only once.
+	single bool
+}
+
+// SyncData returns a copy of the card with those of Kind, Gender and
+// GramGender that are set written into Data, replacing the value of the
+// first datum of each property or, if there's none, added first. Other
+// KIND and GENDER data are dropped, as each may appear only once. The
+// card's own Data isn't changed.
+func (v Vcard) SyncData() Vcard {
+	fields := make(map[string]syncedField)
+	if v.Kind != "" {
+		fields["KIND"] = syncedField{KindDatum(v.Kind), func(d VcardDatum) bool {
+			k, err := ParseKind(d.StringValue)
+			return err == nil && k == v.Kind
+		}, true}
+	}
+	if v.Gender != nil {
+		g := *v.Gender
+		fields["GENDER"] = syncedField{GenderDatum(g), func(d VcardDatum) bool {
+			dg, err := d.Gender()
+			return err == nil && dg == g
+		}, true}
+	}
+	if v.GramGender != "" {
+		fields["GRAMGENDER"] = syncedField{StringDatum("GRAMGENDER", nil, v.GramGender.String()), func(d VcardDatum) bool {
+			g, err := ParseGramGender(d.StringValue)
+			return err == nil && g == v.GramGender
+		}, false}
+	}
+	if len(fields) == 0 {
+		return v
+	}
+	placed := make(map[string]bool)
+	data := make([]VcardDatum, 0, len(v.Data)+len(fields))
+	for _, d := range v.Data {
+		name := strings.ToUpper(d.FieldName)
+		f, ok := fields[name]
+		if ok && placed[name] && f.single {
+			continue
+		}
+		if ok && !placed[name] {
+			placed[name] = true
+			if !f.same(d) {
+				replaced := f.datum
+				replaced.FieldName, replaced.Attrs, replaced.Spelling = d.FieldName, d.Attrs, d.Spelling
+				d = replaced
+			}
+		}
+		data = append(data, d)
+	}
+	var added []VcardDatum
+	for _, name := range []string{"KIND", "GENDER", "GRAMGENDER"} {
+		if f, ok := fields[name]; ok && !placed[name] {
+			added = append(added, f.datum)
+		}
+	}
+	return v.withData(append(added, data...))
+}
+
+// stamp applies the ProdID and StampRev options, replacing the first PRODID
+// and REV where the card has them and otherwise putting them first. Any
+// other PRODID and REV the stamps replace are dropped.
+func (v Vcard) stamp(opts EncodeOptions) (Vcard, error) {
+	var stamps []VcardDatum
+	if opts.ProdID != "" {
+		stamps = append(stamps, StringDatum("PRODID", nil, opts.ProdID))
+	}
+	if opts.StampRev {
+		changed := true
+		if opts.PreviousETag != "" {
+			etag, err := ETag(v)
+			if err != nil {
+				return Vcard{}, err
+			}
+			changed = etag != opts.PreviousETag
+		}
+		if changed {
+			clock := opts.Clock
+			if clock == nil {
+				clock = time.Now
+			}
+			stamps = append(stamps, TimestampDatum("REV", nil, clock()))
+		}
+	}
+	if len(stamps) == 0 {
+		return v, nil
+	}
+	stamped := v.withData(make([]VcardDatum, 0, len(v.Data)+len(stamps)))
+	placed := make([]bool, len(stamps))
+	for _, d := range v.Data {
+		i := indexOfField(stamps, d.FieldName)
+		switch {
+		case i == -1:
+			stamped.Data = append(stamped.Data, d)
+		case !placed[i]:
+			stamped.Data = append(stamped.Data, stamps[i])
+			placed[i] = true
+		}
+	}
+	var unplaced []VcardDatum
+	for i, s := range stamps {
+		if !placed[i] {
+			unplaced = append(unplaced, s)
+		}
+	}
+	stamped.Data = append(unplaced, stamped.Data...)
+	return stamped, nil
+}
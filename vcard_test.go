@@ -1,7 +1,9 @@
 package vcardenc
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -17,8 +19,8 @@ var (
 			StringDatum("PHOTO", map[string][]string{"MEDIATYPE": []string{"image/gif"}}, "http://www.example.com/dir_photos/my_photo.gif"),
 			StringDatum("TEL", map[string][]string{"TYPE": []string{"work", "voice"}, "VALUE": []string{"uri"}}, "tel:+11115551212"),
 			StringDatum("TEL", map[string][]string{"TYPE": []string{"home", "voice"}, "VALUE": []string{"uri"}}, "tel:+14045551212"),
-			SemicolonStructuredDatum("ADR", map[string][]string{"TYPE": []string{"work"}, "LABEL": []string{"\"100 Waters Edge\nBaytown, LA 30314\nUnited States of America\""}}, "", "", "100 Waters Edge", "Baytown", "LA", "30314", "United States of America"),
-			SemicolonStructuredDatum("ADR", map[string][]string{"TYPE": []string{"home"}, "LABEL": []string{"\"42 Plantation St.\nBaytown, LA 30314\nUnited States of America\""}}, "", "", "42 Plantation St.", "Baytown", "LA", "30314", "United States of America"),
+			SemicolonStructuredDatum("ADR", map[string][]string{"TYPE": []string{"work"}, "LABEL": []string{"100 Waters Edge\nBaytown, LA 30314\nUnited States of America"}}, "", "", "100 Waters Edge", "Baytown", "LA", "30314", "United States of America"),
+			SemicolonStructuredDatum("ADR", map[string][]string{"TYPE": []string{"home"}, "LABEL": []string{"42 Plantation St.\nBaytown, LA 30314\nUnited States of America"}}, "", "", "42 Plantation St.", "Baytown", "LA", "30314", "United States of America"),
 			StringDatum("EMAIL", nil, "forrestgump@example.com"),
 			StringDatum("REV", nil, "20080424T195243Z"),
 		},
@@ -32,3 +34,100 @@ func TestSimpleEncode(t *testing.T) {
 	}
 	assert.Equal(t, wikipediaCard, enctest)
 }
+
+func TestEncodeStamps(t *testing.T) {
+	clock := func() time.Time { return time.Date(2024, 2, 29, 12, 30, 0, 0, time.FixedZone("IST", 19800)) }
+	card := Vcard{Data: []VcardDatum{
+		StringDatum("FN", nil, "Joe Bloggs"),
+		StringDatum("PRODID", nil, "-//Someone Else//EN"),
+	}}
+	opts := EncodeOptions{ProdID: "-//Example Corp//vcardenc//EN", StampRev: true, Clock: clock}
+	out, err := card.EncodeWith(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nREV:20240229T070000Z\nFN:Joe Bloggs\nPRODID:-//Example Corp//vcardenc//EN\nEND:VCARD", out)
+	assert.Len(t, card.Data, 2, "the card is untouched")
+
+	stamped, err := ParseVcard(out, nil)
+	assert.Nil(t, err)
+	etag, err := ETag(stamped)
+	assert.Nil(t, err)
+
+	// Unchanged since the last ETag, so REV stays as it was.
+	opts.PreviousETag = etag
+	opts.Clock = time.Now
+	again, err := stamped.EncodeWith(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, out, again)
+
+	stamped.Data = append(stamped.Data, StringDatum("NOTE", nil, "Changed"))
+	changed, err := stamped.EncodeWith(opts)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(changed, "REV:20240229T070000Z"))
+}
+
+func TestEncodeStampsReplaceAll(t *testing.T) {
+	card := Vcard{Data: []VcardDatum{
+		StringDatum("PRODID", nil, "-//One//EN"),
+		StringDatum("FN", nil, "Joe Bloggs"),
+		StringDatum("prodid", nil, "-//Two//EN"),
+	}}
+	out, err := card.EncodeWith(EncodeOptions{ProdID: "-//Example Corp//vcardenc//EN"})
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nPRODID:-//Example Corp//vcardenc//EN\nFN:Joe Bloggs\nEND:VCARD", out)
+}
+
+func TestEncodeVersionDatum(t *testing.T) {
+	card := Vcard{Data: []VcardDatum{
+		StringDatum("VERSION", nil, "3.0"),
+		StringDatum("FN", nil, "Joe Bloggs"),
+	}}
+	out, err := card.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:3.0\nFN:Joe Bloggs\nEND:VCARD", out)
+
+	// Version wins over the datum.
+	card.Version = "4.0"
+	out, err = card.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Joe Bloggs\nEND:VCARD", out)
+}
+
+func TestVcardFields(t *testing.T) {
+	card, err := ParseVcard("BEGIN:VCARD\nVERSION:4.0\nFN:Joe Bloggs\nkind:Individual\nGENDER;LANGUAGE=en:M\nGRAMGENDER:masculine\nGRAMGENDER;LANGUAGE=de:neuter\nEND:VCARD", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, KindIndividual, card.Kind)
+	assert.Equal(t, &Gender{SexMale, ""}, card.Gender)
+	assert.Equal(t, GramGender(GramGenderMasculine), card.GramGender)
+
+	// Unchanged fields leave their data as they were.
+	opts := EncodeOptions{PreserveCase: true}
+	out, err := card.EncodeWith(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Joe Bloggs\nkind:Individual\nGENDER;LANGUAGE=en:M\nGRAMGENDER:masculine\nGRAMGENDER;LANGUAGE=de:neuter\nEND:VCARD", out)
+
+	card.Kind, card.Gender, card.GramGender = KindGroup, &Gender{SexFemale, ""}, GramGenderFeminine
+	out, err = card.EncodeWith(opts)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Joe Bloggs\nkind:group\nGENDER;LANGUAGE=en:F\nGRAMGENDER:feminine\nGRAMGENDER;LANGUAGE=de:neuter\nEND:VCARD", out)
+	assert.Equal(t, "Individual", card.Data[1].StringValue)
+	synced := card.SyncData()
+	assert.Equal(t, synced, synced.SyncFields().SyncData())
+
+	// Empty fields fall back to Data.
+	card.Kind, card.Gender, card.GramGender = "", nil, ""
+	out, err = card.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nFN:Joe Bloggs\nKIND:Individual\nGENDER;LANGUAGE=en:M\nGRAMGENDER:masculine\nGRAMGENDER;LANGUAGE=de:neuter\nEND:VCARD", out)
+
+	// Fields without data are added first, and extra KINDs dropped.
+	card = Vcard{Kind: KindOrg, GramGender: GramGenderNeuter, Data: []VcardDatum{
+		StringDatum("FN", nil, "Acme"),
+		KindDatum(KindOrg),
+		KindDatum(KindGroup),
+	}}
+	out, err = card.Encode(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "BEGIN:VCARD\nVERSION:4.0\nGRAMGENDER:neuter\nFN:Acme\nKIND:org\nEND:VCARD", out)
+	assert.Empty(t, DefaultRegistry.Validate(card))
+	assert.Equal(t, KindOrg, Normalize(card).Kind)
+}